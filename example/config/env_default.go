@@ -10,9 +10,10 @@ func init() {
 			},
 		},
 		Db: ConfigDb{
-			Pass: "",
-			Uri:  "mongdb://localhos:27017/db",
-			User: "",
+			Db_name: "db",
+			Pass:    "",
+			Uri:     "mongdb://localhos:27017/db",
+			User:    "",
 		},
 		EmptyArray: []interface{}{},
 		List:       []map[string]interface{}{map[string]interface{}{"a": 1, "b": 2}, map[string]interface{}{"a": 3, "b": 4}},
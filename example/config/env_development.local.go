@@ -10,9 +10,10 @@ func init() {
 			},
 		},
 		Db: ConfigDb{
-			Pass: "",
-			Uri:  "mongdb://localhost:27017/${project}",
-			User: "",
+			Db_name: "db",
+			Pass:    "",
+			Uri:     "mongdb://localhost:27017/${project}",
+			User:    "",
 		},
 		EmptyArray: []interface{}(nil),
 		List:       []map[string]interface{}{map[string]interface{}{"a": 1, "b": 2}, map[string]interface{}{"a": 3, "b": 4}},
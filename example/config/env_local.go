@@ -10,9 +10,10 @@ func init() {
 			},
 		},
 		Db: ConfigDb{
-			Pass: "norris",
-			Uri:  "mongdb://localhos:27017/db",
-			User: "chuck",
+			Db_name: "db",
+			Pass:    "norris",
+			Uri:     "mongdb://localhos:27017/db",
+			User:    "chuck",
 		},
 		EmptyArray: []interface{}(nil),
 		List:       []map[string]interface{}{map[string]interface{}{"a": 1, "b": 2}, map[string]interface{}{"a": 3, "b": 4}},
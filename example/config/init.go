@@ -5,26 +5,67 @@ package config
 import (
 	"fmt"
 	"os"
-)
-
-var (
-	_ = os.Getenv
-	_ = fmt.Printf
+	"strings"
 )
 
 // Current is the current config, selected by the curren env and
 // updated by the availalbe env vars
 var Current *Config
 
-// This init tries to retrieve the current environment via the
-// common env var 'ENV' and applies activated plugins
-func init() {
-	Current, _ = Get(os.Getenv("ENV"))
+// InitError holds the error of the last call of Init, nil if there was none
+var InitError error
+
+// recordSource records the source of a value, which a plugin set on c, e.g. 'env var PORT'.
+// It does nothing, unless replaced by a plugin like 'provenance'
+var recordSource = func(c *Config, path string, source string) {}
 
-	if errs := Current.UpdateFromEnv(); errs != nil {
-		fmt.Println(errs)
+// Errors holds multiple errors, e.g. the ones reported by the plugins on Init
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
 	}
+	return fmt.Sprintf("%d error(s): %s", len(e), strings.Join(msgs, "; "))
+}
 
-	Current.Substitute()
+// Init sets Current to a copy of the config of the environment
+// given by the common env var 'ENV' and applies activated plugins.
+// The returned error, also stored in InitError, is of type Errors.
+// Init is called on init, but can be called again, e.g. after env vars changed
+func Init() error {
+	c, _ := Get(os.Getenv("ENV"))
+	copied := *c
+	Current = &copied
+	InitError = nil
+	if errs := applyPlugins(Current); len(errs) > 0 {
+		InitError = Errors(errs)
+	}
+	return InitError
+}
+
+// MustInit calls Init and panics, if it fails
+func MustInit() {
+	if err := Init(); err != nil {
+		panic(err)
+	}
+}
+
+// This init calls Init.
+// Errors are printed to stderr, they are also stored in InitError
+func init() {
+	if err := Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "genfig: %v\n", err)
+	}
+}
 
+// applyPlugins calls the init methods of all activated plugins on c
+// and returns all errors they reported
+func applyPlugins(c *Config) []error {
+	errs := []error{}
+	errs = append(errs, c.UpdateFromEnv()...)
+	errs = append(errs, c.substitute()...)
+	c.storeInHolder()
+	return errs
 }
@@ -2,7 +2,9 @@
 
 package config
 
-import "testing"
+import (
+	"testing"
+)
 
 func Test_CurrentConfig(t *testing.T) {
 	if Current == nil {
@@ -0,0 +1,225 @@
+// Code generated by genfig plugin 'deepcopy'; DO NOT EDIT.
+
+package config
+
+// Change is a field, which differs between two configs, see Config.Diff
+type Change struct {
+	// Path is the dotted path of the field, e.g. 'server.port'
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// Clone returns a deep copy of c, which shares no slices or maps with c
+func (c *Config) Clone() *Config {
+	copied := *c
+	copied.EmptyArray = cloneSlice(c.EmptyArray)
+	copied.List = cloneMapSlice(c.List)
+	copied.Secrets = append(c.Secrets[:0:0], c.Secrets...)
+	return &copied
+}
+
+// Equal returns whether all fields of c and other are equal, see Diff
+func (c *Config) Equal(other *Config) bool {
+	return len(c.Diff(other)) == 0
+}
+
+// Diff returns the changes of all fields, which differ between c (old) and other (new),
+// ordered by their paths. Nil and empty slices are considered equal
+func (c *Config) Diff(other *Config) []Change {
+	changes := []Change{}
+	if c.Apis.Google.Uri != other.Apis.Google.Uri {
+		changes = append(changes, Change{"apis.google.uri", c.Apis.Google.Uri, other.Apis.Google.Uri})
+	}
+	if c.Db.Db_name != other.Db.Db_name {
+		changes = append(changes, Change{"db.db_name", c.Db.Db_name, other.Db.Db_name})
+	}
+	if c.Db.Pass != other.Db.Pass {
+		changes = append(changes, Change{"db.pass", c.Db.Pass, other.Db.Pass})
+	}
+	if c.Db.Uri != other.Db.Uri {
+		changes = append(changes, Change{"db.uri", c.Db.Uri, other.Db.Uri})
+	}
+	if c.Db.User != other.Db.User {
+		changes = append(changes, Change{"db.user", c.Db.User, other.Db.User})
+	}
+	if !equalValue(c.EmptyArray, other.EmptyArray) {
+		changes = append(changes, Change{"emptyarray", c.EmptyArray, other.EmptyArray})
+	}
+	if !equalValue(c.List, other.List) {
+		changes = append(changes, Change{"list", c.List, other.List})
+	}
+	if c.LongDesc.De != other.LongDesc.De {
+		changes = append(changes, Change{"longdesc.de", c.LongDesc.De, other.LongDesc.De})
+	}
+	if c.LongDesc.En != other.LongDesc.En {
+		changes = append(changes, Change{"longdesc.en", c.LongDesc.En, other.LongDesc.En})
+	}
+	if c.Project != other.Project {
+		changes = append(changes, Change{"project", c.Project, other.Project})
+	}
+	if c.Randomizer.Threshold != other.Randomizer.Threshold {
+		changes = append(changes, Change{"randomizer.threshold", c.Randomizer.Threshold, other.Randomizer.Threshold})
+	}
+	if !equalValue(c.Secrets, other.Secrets) {
+		changes = append(changes, Change{"secrets", c.Secrets, other.Secrets})
+	}
+	if c.Server.Host != other.Server.Host {
+		changes = append(changes, Change{"server.host", c.Server.Host, other.Server.Host})
+	}
+	if c.Server.Port != other.Server.Port {
+		changes = append(changes, Change{"server.port", c.Server.Port, other.Server.Port})
+	}
+	if c.Version != other.Version {
+		changes = append(changes, Change{"version", c.Version, other.Version})
+	}
+	if c.Wip != other.Wip {
+		changes = append(changes, Change{"wip", c.Wip, other.Wip})
+	}
+	return changes
+}
+
+// cloneValue returns a deep copy of v, if it is a slice or a map
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return cloneSlice(v)
+	case map[string]interface{}:
+		return cloneMap(v)
+	case []map[string]interface{}:
+		return cloneMapSlice(v)
+	}
+	return v
+}
+
+// cloneSlice returns a deep copy of s
+func cloneSlice(s []interface{}) []interface{} {
+	if s == nil {
+		return nil
+	}
+	copied := make([]interface{}, len(s))
+	for i, v := range s {
+		copied[i] = cloneValue(v)
+	}
+	return copied
+}
+
+// cloneMap returns a deep copy of m
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		copied[k] = cloneValue(v)
+	}
+	return copied
+}
+
+// cloneMapSlice returns a deep copy of s
+func cloneMapSlice(s []map[string]interface{}) []map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+	copied := make([]map[string]interface{}, len(s))
+	for i, m := range s {
+		copied[i] = cloneMap(m)
+	}
+	return copied
+}
+
+// equalValue returns whether a and b are deeply equal,
+// both have to be of a type used by the fields of Config
+func equalValue(a, b interface{}) bool {
+	switch a := a.(type) {
+	case []string:
+		b, ok := b.([]string)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []int64:
+		b, ok := b.([]int64)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []uint64:
+		b, ok := b.([]uint64)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []float64:
+		b, ok := b.([]float64)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []bool:
+		b, ok := b.([]bool)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalValue(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case []map[string]interface{}:
+		b, ok := b.([]map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalValue(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, found := b[k]; !found || !equalValue(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
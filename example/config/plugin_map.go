@@ -2,16 +2,305 @@
 
 package config
 
-import "encoding/json"
+import (
+	"fmt"
+	"strings"
+)
 
+// AsMap converts the config into a map, keyed by the names of the fields.
+// Values are not copied, so slices are shared with the config
 func (c *Config) AsMap() map[string]interface{} {
-	marshaled, err := json.Marshal(c)
-	if err != nil {
-		return nil
+	return c.asMap()
+}
+
+func (c *Config) asMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Apis":       c.Apis.asMap(),
+		"Db":         c.Db.asMap(),
+		"EmptyArray": c.EmptyArray,
+		"List":       c.List,
+		"LongDesc":   c.LongDesc.asMap(),
+		"Project":    c.Project,
+		"Randomizer": c.Randomizer.asMap(),
+		"Secrets":    c.Secrets,
+		"Server":     c.Server.asMap(),
+		"Version":    c.Version,
+		"Wip":        c.Wip,
+	}
+}
+
+func (c *ConfigApis) asMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Google": c.Google.asMap(),
+	}
+}
+
+func (c *ConfigApisGoogle) asMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Uri": c.Uri,
+	}
+}
+
+func (c *ConfigDb) asMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Db_name": c.Db_name,
+		"Pass":    c.Pass,
+		"Uri":     c.Uri,
+		"User":    c.User,
+	}
+}
+
+func (c *ConfigLongDesc) asMap() map[string]interface{} {
+	return map[string]interface{}{
+		"De": c.De,
+		"En": c.En,
+	}
+}
+
+func (c *ConfigRandomizer) asMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Threshold": c.Threshold,
+	}
+}
+
+func (c *ConfigServer) asMap() map[string]interface{} {
+	return map[string]interface{}{
+		"Host": c.Host,
+		"Port": c.Port,
+	}
+}
+
+// Paths returns the dotted paths of all fields, which are no structs, e.g. 'server.port'
+func (c *Config) Paths() []string {
+	return []string{
+		"apis.google.uri",
+		"db.db_name",
+		"db.pass",
+		"db.uri",
+		"db.user",
+		"emptyarray",
+		"list",
+		"longdesc.de",
+		"longdesc.en",
+		"project",
+		"randomizer.threshold",
+		"secrets",
+		"server.host",
+		"server.port",
+		"version",
+		"wip",
+	}
+}
+
+// GetPath returns the value of the field at the dotted path, e.g. 'server.port'
+func (c *Config) GetPath(path string) (interface{}, error) {
+	switch strings.ToLower(path) {
+	case "apis":
+		return c.Apis, nil
+	case "apis.google":
+		return c.Apis.Google, nil
+	case "apis.google.uri":
+		return c.Apis.Google.Uri, nil
+	case "db":
+		return c.Db, nil
+	case "db.db_name":
+		return c.Db.Db_name, nil
+	case "db.pass":
+		return c.Db.Pass, nil
+	case "db.uri":
+		return c.Db.Uri, nil
+	case "db.user":
+		return c.Db.User, nil
+	case "emptyarray":
+		return c.EmptyArray, nil
+	case "list":
+		return c.List, nil
+	case "longdesc":
+		return c.LongDesc, nil
+	case "longdesc.de":
+		return c.LongDesc.De, nil
+	case "longdesc.en":
+		return c.LongDesc.En, nil
+	case "project":
+		return c.Project, nil
+	case "randomizer":
+		return c.Randomizer, nil
+	case "randomizer.threshold":
+		return c.Randomizer.Threshold, nil
+	case "secrets":
+		return c.Secrets, nil
+	case "server":
+		return c.Server, nil
+	case "server.host":
+		return c.Server.Host, nil
+	case "server.port":
+		return c.Server.Port, nil
+	case "version":
+		return c.Version, nil
+	case "wip":
+		return c.Wip, nil
 	}
-	m := map[string]interface{}{}
-	if err := json.Unmarshal(marshaled, &m); err != nil {
+	return nil, fmt.Errorf("unknown path '%s'", path)
+}
+
+// SetPath sets the field at the dotted path, e.g. 'server.port', to value,
+// which has to be of the exact type of the field, e.g. int64 for 'server.port'
+func (c *Config) SetPath(path string, value interface{}) error {
+	switch strings.ToLower(path) {
+	case "apis":
+		v, ok := value.(ConfigApis)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected ConfigApis", value, value, path)
+		}
+		c.Apis = v
+		return nil
+	case "apis.google":
+		v, ok := value.(ConfigApisGoogle)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected ConfigApisGoogle", value, value, path)
+		}
+		c.Apis.Google = v
+		return nil
+	case "apis.google.uri":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.Apis.Google.Uri = v
+		return nil
+	case "db":
+		v, ok := value.(ConfigDb)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected ConfigDb", value, value, path)
+		}
+		c.Db = v
+		return nil
+	case "db.db_name":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.Db.Db_name = v
+		return nil
+	case "db.pass":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.Db.Pass = v
+		return nil
+	case "db.uri":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.Db.Uri = v
+		return nil
+	case "db.user":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.Db.User = v
+		return nil
+	case "emptyarray":
+		v, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected []interface {}", value, value, path)
+		}
+		c.EmptyArray = v
+		return nil
+	case "list":
+		v, ok := value.([]map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected []map[string]interface {}", value, value, path)
+		}
+		c.List = v
+		return nil
+	case "longdesc":
+		v, ok := value.(ConfigLongDesc)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected ConfigLongDesc", value, value, path)
+		}
+		c.LongDesc = v
+		return nil
+	case "longdesc.de":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.LongDesc.De = v
+		return nil
+	case "longdesc.en":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.LongDesc.En = v
+		return nil
+	case "project":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.Project = v
+		return nil
+	case "randomizer":
+		v, ok := value.(ConfigRandomizer)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected ConfigRandomizer", value, value, path)
+		}
+		c.Randomizer = v
+		return nil
+	case "randomizer.threshold":
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected float64", value, value, path)
+		}
+		c.Randomizer.Threshold = v
+		return nil
+	case "secrets":
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected []string", value, value, path)
+		}
+		c.Secrets = v
+		return nil
+	case "server":
+		v, ok := value.(ConfigServer)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected ConfigServer", value, value, path)
+		}
+		c.Server = v
+		return nil
+	case "server.host":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.Server.Host = v
+		return nil
+	case "server.port":
+		v, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected int64", value, value, path)
+		}
+		c.Server.Port = v
+		return nil
+	case "version":
+		v, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected string", value, value, path)
+		}
+		c.Version = v
+		return nil
+	case "wip":
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected bool", value, value, path)
+		}
+		c.Wip = v
 		return nil
 	}
-	return m
+	return fmt.Errorf("unknown path '%s'", path)
 }
@@ -0,0 +1,129 @@
+// Code generated by genfig plugin 'redact'; DO NOT EDIT.
+
+package config
+
+import (
+	"fmt"
+)
+
+// redactedValue replaces the values of secret fields, when a config is printed or logged
+const redactedValue = "[redacted]"
+
+// String returns the fields of c like the verb %+v does, but with the values of all secret fields
+// replaced by redactedValue, so that c can be printed safely. Empty values are kept
+func (c Config) String() string {
+	return fmt.Sprintf("{Apis:%v Db:%v EmptyArray:%v List:%v LongDesc:%v Project:%v Randomizer:%v Secrets:%v Server:%v Version:%v Wip:%v}", c.Apis, c.Db, c.EmptyArray, c.List, c.LongDesc, c.Project, c.Randomizer, redact(c.Secrets), c.Server, c.Version, c.Wip)
+}
+
+// GoString returns the fields of c like the verb %#v does, but with the values of all
+// secret fields replaced by redactedValue. Empty values are kept
+func (c Config) GoString() string {
+	return fmt.Sprintf("%T{Apis:%#v, Db:%#v, EmptyArray:%#v, List:%#v, LongDesc:%#v, Project:%#v, Randomizer:%#v, Secrets:%#v, Server:%#v, Version:%#v, Wip:%#v}", c, c.Apis, c.Db, c.EmptyArray, c.List, c.LongDesc, c.Project, c.Randomizer, redact(c.Secrets), c.Server, c.Version, c.Wip)
+}
+
+// String returns the fields of c like the verb %+v does, but with the values of all secret fields
+// replaced by redactedValue, so that c can be printed safely. Empty values are kept
+func (c ConfigApis) String() string {
+	return fmt.Sprintf("{Google:%v}", c.Google)
+}
+
+// GoString returns the fields of c like the verb %#v does, but with the values of all
+// secret fields replaced by redactedValue. Empty values are kept
+func (c ConfigApis) GoString() string {
+	return fmt.Sprintf("%T{Google:%#v}", c, c.Google)
+}
+
+// String returns the fields of c like the verb %+v does, but with the values of all secret fields
+// replaced by redactedValue, so that c can be printed safely. Empty values are kept
+func (c ConfigApisGoogle) String() string {
+	return fmt.Sprintf("{Uri:%v}", c.Uri)
+}
+
+// GoString returns the fields of c like the verb %#v does, but with the values of all
+// secret fields replaced by redactedValue. Empty values are kept
+func (c ConfigApisGoogle) GoString() string {
+	return fmt.Sprintf("%T{Uri:%#v}", c, c.Uri)
+}
+
+// String returns the fields of c like the verb %+v does, but with the values of all secret fields
+// replaced by redactedValue, so that c can be printed safely. Empty values are kept
+func (c ConfigDb) String() string {
+	return fmt.Sprintf("{Db_name:%v Pass:%v Uri:%v User:%v}", c.Db_name, redact(c.Pass), c.Uri, c.User)
+}
+
+// GoString returns the fields of c like the verb %#v does, but with the values of all
+// secret fields replaced by redactedValue. Empty values are kept
+func (c ConfigDb) GoString() string {
+	return fmt.Sprintf("%T{Db_name:%#v, Pass:%#v, Uri:%#v, User:%#v}", c, c.Db_name, redact(c.Pass), c.Uri, c.User)
+}
+
+// String returns the fields of c like the verb %+v does, but with the values of all secret fields
+// replaced by redactedValue, so that c can be printed safely. Empty values are kept
+func (c ConfigLongDesc) String() string {
+	return fmt.Sprintf("{De:%v En:%v}", c.De, c.En)
+}
+
+// GoString returns the fields of c like the verb %#v does, but with the values of all
+// secret fields replaced by redactedValue. Empty values are kept
+func (c ConfigLongDesc) GoString() string {
+	return fmt.Sprintf("%T{De:%#v, En:%#v}", c, c.De, c.En)
+}
+
+// String returns the fields of c like the verb %+v does, but with the values of all secret fields
+// replaced by redactedValue, so that c can be printed safely. Empty values are kept
+func (c ConfigRandomizer) String() string {
+	return fmt.Sprintf("{Threshold:%v}", c.Threshold)
+}
+
+// GoString returns the fields of c like the verb %#v does, but with the values of all
+// secret fields replaced by redactedValue. Empty values are kept
+func (c ConfigRandomizer) GoString() string {
+	return fmt.Sprintf("%T{Threshold:%#v}", c, c.Threshold)
+}
+
+// String returns the fields of c like the verb %+v does, but with the values of all secret fields
+// replaced by redactedValue, so that c can be printed safely. Empty values are kept
+func (c ConfigServer) String() string {
+	return fmt.Sprintf("{Host:%v Port:%v}", c.Host, c.Port)
+}
+
+// GoString returns the fields of c like the verb %#v does, but with the values of all
+// secret fields replaced by redactedValue. Empty values are kept
+func (c ConfigServer) GoString() string {
+	return fmt.Sprintf("%T{Host:%#v, Port:%#v}", c, c.Host, c.Port)
+}
+
+// redact returns redactedValue instead of v, unless v is empty
+func redact(v interface{}) interface{} {
+	empty := false
+	switch v := v.(type) {
+	case string:
+		empty = v == ""
+	case int64:
+		empty = v == 0
+	case uint64:
+		empty = v == 0
+	case float64:
+		empty = v == 0
+	case bool:
+		empty = !v
+	case []string:
+		empty = len(v) == 0
+	case []int64:
+		empty = len(v) == 0
+	case []uint64:
+		empty = len(v) == 0
+	case []float64:
+		empty = len(v) == 0
+	case []bool:
+		empty = len(v) == 0
+	case []interface{}:
+		empty = len(v) == 0
+	case []map[string]interface{}:
+		empty = len(v) == 0
+	}
+	if empty {
+		return v
+	}
+	return redactedValue
+}
@@ -0,0 +1,81 @@
+// Code generated by genfig plugin 'redact'; DO NOT EDIT.
+
+//go:build go1.21
+// +build go1.21
+
+package config
+
+import (
+	"log/slog"
+)
+
+// LogValue returns the fields of c as group, keyed by their lowercase names,
+// with the values of all secret fields replaced by redactedValue
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("apis", c.Apis),
+		slog.Any("db", c.Db),
+		slog.Any("emptyarray", c.EmptyArray),
+		slog.Any("list", c.List),
+		slog.Any("longdesc", c.LongDesc),
+		slog.Any("project", c.Project),
+		slog.Any("randomizer", c.Randomizer),
+		slog.Any("secrets", redact(c.Secrets)),
+		slog.Any("server", c.Server),
+		slog.Any("version", c.Version),
+		slog.Any("wip", c.Wip),
+	)
+}
+
+// LogValue returns the fields of c as group, keyed by their lowercase names,
+// with the values of all secret fields replaced by redactedValue
+func (c ConfigApis) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("google", c.Google),
+	)
+}
+
+// LogValue returns the fields of c as group, keyed by their lowercase names,
+// with the values of all secret fields replaced by redactedValue
+func (c ConfigApisGoogle) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("uri", c.Uri),
+	)
+}
+
+// LogValue returns the fields of c as group, keyed by their lowercase names,
+// with the values of all secret fields replaced by redactedValue
+func (c ConfigDb) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("db_name", c.Db_name),
+		slog.Any("pass", redact(c.Pass)),
+		slog.Any("uri", c.Uri),
+		slog.Any("user", c.User),
+	)
+}
+
+// LogValue returns the fields of c as group, keyed by their lowercase names,
+// with the values of all secret fields replaced by redactedValue
+func (c ConfigLongDesc) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("de", c.De),
+		slog.Any("en", c.En),
+	)
+}
+
+// LogValue returns the fields of c as group, keyed by their lowercase names,
+// with the values of all secret fields replaced by redactedValue
+func (c ConfigRandomizer) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("threshold", c.Threshold),
+	)
+}
+
+// LogValue returns the fields of c as group, keyed by their lowercase names,
+// with the values of all secret fields replaced by redactedValue
+func (c ConfigServer) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Any("host", c.Host),
+		slog.Any("port", c.Port),
+	)
+}
@@ -3,226 +3,346 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-var _ = strings.Contains
+// substitutionReferences holds the references of non-string fields of the env configs,
+// e.g. 'port: ${PORT}', by their dotted paths. The fields themselves hold zero values
+var substitutionReferences = map[*Config]map[string]string{
+	&Envs.Default:          {},
+	&Envs.Development:      {},
+	&Envs.DevelopmentLocal: {},
+	&Envs.Local:            {},
+	&Envs.Production:       {},
+	&Envs.Test:             {},
+}
 
-const (
-	maxSubstitutionIteraions = 5
-)
+// substitutionPaths are the dotted paths of all string fields
+var substitutionPaths = []string{
+	"apis.google.uri",
+	"db.db_name",
+	"db.pass",
+	"db.uri",
+	"db.user",
+	"longdesc.de",
+	"longdesc.en",
+	"project",
+	"server.host",
+	"version",
+}
 
-var (
-	raw Config
-)
+// substitutionEnvName matches the names of env vars, which can be referenced
+var substitutionEnvName = regexp.MustCompile(`^\w+$`)
 
-// Substitute replaces all.
-// The return value informs, whether all substitutions could be
-// applied within {maxSubstitutionIteraions} or not
+// substitutionState is the state of the last substitution of a config.
+// It is never changed, but replaced, as copies of a config share it
+type substitutionState struct {
+	// raw is a copy of the config before the substitution was applied
+	raw *Config
+	// result is a copy of the config after the substitution was applied
+	result *Config
+	// overridden are the paths of non-string fields, which were changed after
+	// a substitution, so that their references are not applied anymore
+	overridden map[string]bool
+	errs       []error
+}
+
+// Substitute replaces all references in string fields by their values.
+// References are written as '${name}', where name is either the dotted path
+// of another field, e.g. '${server.port}', or an env var, e.g. '${HOME}'.
+// '${name:-default}' uses default, if name is unset or empty, '${name:?message}'
+// fails with message then. '$${' is kept as literal '${'.
+// Non-string fields of env configs can consist of a single reference,
+// e.g. 'port: ${PORT}', whose value has to match the type of the field.
+// If c was substituted before, the references of fields, which were not changed since,
+// are substituted again, while changed fields (e.g. by UpdateFromEnv) keep their values.
+// The return value informs, whether all substitutions could be applied,
+// the failures (e.g. unknown references, type mismatches or cycles) are returned by SubstitutionErrors
 func (c *Config) Substitute() bool {
-	c.ResetSubstitution()
+	return len(c.substitute()) == 0
+}
+
+// SubstitutionErrors returns the failures of the last substitution of c
+func (c *Config) SubstitutionErrors() []error {
+	if c.lastSubstitution == nil {
+		return nil
+	}
+	return append([]error{}, c.lastSubstitution.errs...)
+}
+
+// ResetSubstitution resets the configuration to the state,
+// before its last substitution was applied
+func (c *Config) ResetSubstitution() {
+	if c.lastSubstitution != nil {
+		*c = *c.lastSubstitution.raw.Clone()
+	}
+}
+
+// substitute applies the substitution to c and returns all failures
+func (c *Config) substitute() []error {
+	// backup the "raw" configuration, restoring the raw values of all fields,
+	// which were substituted last time and not changed since
+	state := &substitutionState{raw: c.Clone(), overridden: map[string]bool{}}
+	state.raw.lastSubstitution = nil
+	refs := c.substitutionReferences()
+	if last := c.lastSubstitution; last != nil {
+		changed := map[string]bool{}
+		for _, change := range last.result.Diff(c) {
+			changed[change.Path] = true
+		}
+		for path := range last.overridden {
+			state.overridden[path] = true
+		}
+		for _, change := range last.raw.Diff(last.result) {
+			if !changed[change.Path] {
+				value, _, _ := last.raw.substitutionValue(change.Path)
+				_ = state.raw.setSubstituted(change.Path, value)
+			}
+		}
+		for path := range refs {
+			if changed[path] {
+				state.overridden[path] = true
+			}
+		}
+		*c = *state.raw.Clone()
+	}
+
+	s := &substitution{
+		c:        c,
+		refs:     map[string]string{},
+		resolved: map[string]string{},
+		failed:   map[string]error{},
+	}
+	for path, ref := range refs {
+		if !state.overridden[path] {
+			s.refs[path] = ref
+		}
+	}
+	paths := append([]string{}, substitutionPaths...)
+	refPaths := []string{}
+	for path := range s.refs {
+		refPaths = append(refPaths, path)
+	}
+	sort.Strings(refPaths)
+	reported := map[error]bool{}
+	for _, path := range append(paths, refPaths...) {
+		if _, err := s.resolve(path); err != nil && !reported[err] {
+			reported[err] = true
+			state.errs = append(state.errs, err)
+		}
+	}
+	state.result = c.Clone()
+	c.lastSubstitution = state
+	return state.errs
+}
+
+// substitutionReferences returns the references of the non-string fields of c, which are
+// the ones of c, if it is an env config, or the ones of the env config selected by 'ENV'
+func (c *Config) substitutionReferences() map[string]string {
+	for _, env := range envMap {
+		if env == c {
+			return substitutionReferences[c]
+		}
+	}
+	env, _ := Get(os.Getenv("ENV"))
+	return substitutionReferences[env]
+}
+
+// substitution holds the state of a single Substitute call
+type substitution struct {
+	c        *Config
+	refs     map[string]string
+	resolved map[string]string
+	failed   map[string]error
+	// stack holds the paths of the fields being resolved, to detect cycles
+	stack []string
+}
 
-	// backup the "raw" configuration
-	raw = *c
+// resolve substitutes the field at path, if necessary, and returns its value
+func (s *substitution) resolve(path string) (string, error) {
+	if value, done := s.resolved[path]; done {
+		return value, nil
+	}
+	if err, failed := s.failed[path]; failed {
+		return "", err
+	}
+	for i, p := range s.stack {
+		if p == path {
+			cycle := append(append([]string{}, s.stack[i:]...), path)
+			return "", substitutionError(path, "cycle %s", strings.Join(cycle, " -> "))
+		}
+	}
+	value, isString, _ := s.c.substitutionValue(path)
+	ref, isRef := s.refs[path]
+	if !isString && !isRef {
+		s.resolved[path] = value
+		return value, nil
+	}
+	if isRef {
+		value = ref
+	}
+	s.stack = append(s.stack, path)
+	expanded, err := s.expand(path, value)
+	s.stack = s.stack[:len(s.stack)-1]
+	if err == nil {
+		err = s.c.setSubstituted(path, expanded)
+	}
+	if err != nil {
+		s.failed[path] = err
+		return "", err
+	}
+	if expanded != value {
+		recordSource(s.c, path, fmt.Sprintf("substitution of '%s'", value))
+	}
+	s.resolved[path] = expanded
+	return expanded, nil
+}
 
-	run := 0
+// expand replaces all references in value, which is the value of the field at path
+func (s *substitution) expand(path, value string) (string, error) {
+	out := strings.Builder{}
 	for {
-		if run == maxSubstitutionIteraions {
-			return false
+		i := strings.Index(value, "${")
+		if i < 0 {
+			out.WriteString(value)
+			return out.String(), nil
+		}
+		if i > 0 && value[i-1] == '$' {
+			out.WriteString(value[:i-1] + "${")
+			value = value[i+2:]
+			continue
 		}
-		if c.substitute() == 0 {
-			return true
+		end := strings.Index(value[i:], "}")
+		if end < 0 {
+			return "", substitutionError(path, "missing '}' in '%s'", value[i:])
 		}
-		run += 1
+		replaced, err := s.lookup(path, value[i+2:i+end])
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(value[:i] + replaced)
+		value = value[i+end+1:]
 	}
 }
 
-// ResetSubstitution resets the configuration to the state,
-// before the substitution was applied
-func (c *Config) ResetSubstitution() {
-	c = &raw
+// lookup returns the value of the reference expr, e.g. 'server.host' or 'PORT:-8080',
+// which is used by the field at path
+func (s *substitution) lookup(path, expr string) (string, error) {
+	name, op, arg := expr, "", ""
+	if i := strings.Index(expr, ":"); i >= 0 && i+1 < len(expr) && (expr[i+1] == '-' || expr[i+1] == '?') {
+		name, op, arg = expr[:i], expr[i:i+2], expr[i+2:]
+	}
+	value, found := "", false
+	if _, _, known := s.c.substitutionValue(name); known {
+		v, err := s.resolve(name)
+		if err != nil {
+			return "", err
+		}
+		value, found = v, true
+	} else if substitutionEnvName.MatchString(name) {
+		value, found = os.LookupEnv(name)
+	}
+	if value != "" || (found && op == "") {
+		return value, nil
+	}
+	switch op {
+	case ":-":
+		return arg, nil
+	case ":?":
+		if arg == "" {
+			arg = "not set"
+		}
+		return "", substitutionError(path, "${%s}: %s", name, arg)
+	}
+	return "", substitutionError(path, "unknown reference ${%s}", name)
+}
+
+// substitutionError returns an error of the substitution of the field at path
+func substitutionError(path string, format string, args ...interface{}) error {
+	return fmt.Errorf("could not substitute '%s': %s", path, fmt.Sprintf(format, args...))
+}
+
+// substitutionValue returns the value of the field at path as string, whether the field
+// is a string field and whether it can be referenced at all, i.e. is no slice
+func (c *Config) substitutionValue(path string) (value string, isString bool, known bool) {
+	switch path {
+	case "apis.google.uri":
+		return c.Apis.Google.Uri, true, true
+	case "db.db_name":
+		return c.Db.Db_name, true, true
+	case "db.pass":
+		return c.Db.Pass, true, true
+	case "db.uri":
+		return c.Db.Uri, true, true
+	case "db.user":
+		return c.Db.User, true, true
+	case "longdesc.de":
+		return c.LongDesc.De, true, true
+	case "longdesc.en":
+		return c.LongDesc.En, true, true
+	case "project":
+		return c.Project, true, true
+	case "randomizer.threshold":
+		return strconv.FormatFloat(c.Randomizer.Threshold, 'g', -1, 64), false, true
+	case "server.host":
+		return c.Server.Host, true, true
+	case "server.port":
+		return strconv.FormatInt(c.Server.Port, 10), false, true
+	case "version":
+		return c.Version, true, true
+	case "wip":
+		return strconv.FormatBool(c.Wip), false, true
+	}
+	return "", false, false
 }
 
-// substitute tries to replace all substitutions in strings
-func (c *Config) substitute() int {
-	cnt := 0
-
-	envReplacer := regexp.MustCompile(`\${\w+}`)
-
-	r := strings.NewReplacer(
-		"${apis.google.uri}", c.Apis.Google.Uri,
-
-		"${db.pass}", c.Db.Pass,
-
-		"${db.uri}", c.Db.Uri,
-
-		"${db.user}", c.Db.User,
-
-		"${longdesc.de}", c.LongDesc.De,
-
-		"${longdesc.en}", c.LongDesc.En,
-
-		"${project}", c.Project,
-
-		"${server.host}", c.Server.Host,
-
-		"${version}", c.Version,
-	)
-
-	if strings.Contains(c.Apis.Google.Uri, "${") {
-		cnt += 1
-		c.Apis.Google.Uri = r.Replace(c.Apis.Google.Uri)
-		if !strings.Contains(c.Apis.Google.Uri, "${") {
-			cnt -= 1
-		} else {
-			c.Apis.Google.Uri = envReplacer.ReplaceAllStringFunc(c.Apis.Google.Uri, func(in string) (out string) {
-				out = in
-				envName := in[2 : len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
-		}
-	}
-
-	if strings.Contains(c.Db.Pass, "${") {
-		cnt += 1
-		c.Db.Pass = r.Replace(c.Db.Pass)
-		if !strings.Contains(c.Db.Pass, "${") {
-			cnt -= 1
-		} else {
-			c.Db.Pass = envReplacer.ReplaceAllStringFunc(c.Db.Pass, func(in string) (out string) {
-				out = in
-				envName := in[2 : len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
-		}
-	}
-
-	if strings.Contains(c.Db.Uri, "${") {
-		cnt += 1
-		c.Db.Uri = r.Replace(c.Db.Uri)
-		if !strings.Contains(c.Db.Uri, "${") {
-			cnt -= 1
-		} else {
-			c.Db.Uri = envReplacer.ReplaceAllStringFunc(c.Db.Uri, func(in string) (out string) {
-				out = in
-				envName := in[2 : len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
-		}
-	}
-
-	if strings.Contains(c.Db.User, "${") {
-		cnt += 1
-		c.Db.User = r.Replace(c.Db.User)
-		if !strings.Contains(c.Db.User, "${") {
-			cnt -= 1
-		} else {
-			c.Db.User = envReplacer.ReplaceAllStringFunc(c.Db.User, func(in string) (out string) {
-				out = in
-				envName := in[2 : len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
-		}
-	}
-
-	if strings.Contains(c.LongDesc.De, "${") {
-		cnt += 1
-		c.LongDesc.De = r.Replace(c.LongDesc.De)
-		if !strings.Contains(c.LongDesc.De, "${") {
-			cnt -= 1
-		} else {
-			c.LongDesc.De = envReplacer.ReplaceAllStringFunc(c.LongDesc.De, func(in string) (out string) {
-				out = in
-				envName := in[2 : len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
-		}
-	}
-
-	if strings.Contains(c.LongDesc.En, "${") {
-		cnt += 1
-		c.LongDesc.En = r.Replace(c.LongDesc.En)
-		if !strings.Contains(c.LongDesc.En, "${") {
-			cnt -= 1
-		} else {
-			c.LongDesc.En = envReplacer.ReplaceAllStringFunc(c.LongDesc.En, func(in string) (out string) {
-				out = in
-				envName := in[2 : len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
-		}
-	}
-
-	if strings.Contains(c.Project, "${") {
-		cnt += 1
-		c.Project = r.Replace(c.Project)
-		if !strings.Contains(c.Project, "${") {
-			cnt -= 1
-		} else {
-			c.Project = envReplacer.ReplaceAllStringFunc(c.Project, func(in string) (out string) {
-				out = in
-				envName := in[2 : len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
-		}
-	}
-
-	if strings.Contains(c.Server.Host, "${") {
-		cnt += 1
-		c.Server.Host = r.Replace(c.Server.Host)
-		if !strings.Contains(c.Server.Host, "${") {
-			cnt -= 1
-		} else {
-			c.Server.Host = envReplacer.ReplaceAllStringFunc(c.Server.Host, func(in string) (out string) {
-				out = in
-				envName := in[2 : len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
-		}
-	}
-
-	if strings.Contains(c.Version, "${") {
-		cnt += 1
-		c.Version = r.Replace(c.Version)
-		if !strings.Contains(c.Version, "${") {
-			cnt -= 1
-		} else {
-			c.Version = envReplacer.ReplaceAllStringFunc(c.Version, func(in string) (out string) {
-				out = in
-				envName := in[2 : len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
-		}
-	}
-
-	return cnt
+// setSubstituted sets the field at path to the substituted value,
+// which is parsed, if the field is no string field
+func (c *Config) setSubstituted(path string, value string) error {
+	switch path {
+	case "apis.google.uri":
+		c.Apis.Google.Uri = value
+	case "db.db_name":
+		c.Db.Db_name = value
+	case "db.pass":
+		c.Db.Pass = value
+	case "db.uri":
+		c.Db.Uri = value
+	case "db.user":
+		c.Db.User = value
+	case "longdesc.de":
+		c.LongDesc.De = value
+	case "longdesc.en":
+		c.LongDesc.En = value
+	case "project":
+		c.Project = value
+	case "randomizer.threshold":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return substitutionError(path, "invalid value '%s', expected float64", value)
+		}
+		c.Randomizer.Threshold = v
+	case "server.host":
+		c.Server.Host = value
+	case "server.port":
+		v, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return substitutionError(path, "invalid value '%s', expected int64", value)
+		}
+		c.Server.Port = v
+	case "version":
+		c.Version = value
+	case "wip":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return substitutionError(path, "invalid value '%s', expected bool", value)
+		}
+		c.Wip = v
+	}
+	return nil
 }
@@ -5,305 +5,291 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 )
 
-var (
-	_ = os.LookupEnv
-	_ = fmt.Sprintf
-	_ = json.Marshal
-)
+// secretsDir holds files named like the env vars, which contain their values
+const secretsDir = ""
 
+// UpdateFromEnv overrides all fields, for which an env var is set.
+// If not, the value is read from the file given by the env var <NAME>_FILE
+// or from the file named like the env var in the secrets dir, if configured.
+// Values, which cannot be parsed into the type of their field, and files, which
+// cannot be read, are reported as errors, leaving the field unchanged
 func (c *Config) UpdateFromEnv() []error {
-	var val string
-	_ = val
-	var exists bool
-	_ = exists
-	var envs []string
-	_ = envs
-	var errors = []error{}
-
-	envs = []string{"apis.google.uri", "APIS_GOOGLE_URI"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
+	errs := []error{}
+
+	if src, val, exists, err := lookupEnv("apis.google.uri", "APIS_GOOGLE_URI"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
 		c.Apis.Google.Uri = val
+		recordSource(c, "apis.google.uri", src)
 	}
 
-	envs = []string{"db.pass", "DB_PASS"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
+	if src, val, exists, err := lookupEnv("db.db_name", "DB_DB_NAME"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
+		c.Db.Db_name = val
+		recordSource(c, "db.db_name", src)
 	}
-	if exists {
+
+	if src, val, exists, err := lookupEnv("db.pass", "DB_PASS"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
 		c.Db.Pass = val
+		recordSource(c, "db.pass", src)
 	}
 
-	envs = []string{"db.uri", "DB_URI"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
+	if src, val, exists, err := lookupEnv("db.uri", "DB_URI"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
 		c.Db.Uri = val
+		recordSource(c, "db.uri", src)
 	}
 
-	envs = []string{"db.user", "DB_USER"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
+	if src, val, exists, err := lookupEnv("db.user", "DB_USER"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
 		c.Db.User = val
+		recordSource(c, "db.user", src)
 	}
 
-	envs = []string{"emptyarray", "EMPTYARRAY"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
-		if err := parseInterfaceSlice(val, &c.EmptyArray); err != nil {
-			errors = append(errors, fmt.Errorf("Genfig: could not parse []interface {} from CONFIG_EMPTYARRAY ('%s')\n", val))
+	if src, val, exists, err := lookupEnv("emptyarray", "EMPTYARRAY"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
+		if err = parseInterfaceSlice(val, &c.EmptyArray); err != nil {
+			errs = append(errs, envError(src, val, "[]interface {}", err))
+		} else {
+			recordSource(c, "emptyarray", src)
 		}
 	}
 
-	envs = []string{"list", "LIST"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
-		if err := parseMapSlice(val, &c.List); err != nil {
-			errors = append(errors, fmt.Errorf("Genfig: could not parse []map[string]interface {} from CONFIG_LIST ('%s')\n", val))
+	if src, val, exists, err := lookupEnv("list", "LIST"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
+		if err = parseMapSlice(val, &c.List); err != nil {
+			errs = append(errs, envError(src, val, "[]map[string]interface {}", err))
+		} else {
+			recordSource(c, "list", src)
 		}
 	}
 
-	envs = []string{"longdesc.de", "LONGDESC_DE"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
+	if src, val, exists, err := lookupEnv("longdesc.de", "LONGDESC_DE"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
 		c.LongDesc.De = val
+		recordSource(c, "longdesc.de", src)
 	}
 
-	envs = []string{"longdesc.en", "LONGDESC_EN"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
+	if src, val, exists, err := lookupEnv("longdesc.en", "LONGDESC_EN"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
 		c.LongDesc.En = val
+		recordSource(c, "longdesc.en", src)
 	}
 
-	envs = []string{"project", "PROJECT"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
+	if src, val, exists, err := lookupEnv("project", "PROJECT"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
 		c.Project = val
+		recordSource(c, "project", src)
 	}
 
-	envs = []string{"randomizer.threshold", "RANDOMIZER_THRESHOLD"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
-		if err := parseFloat64(val, &c.Randomizer.Threshold); err != nil {
-			errors = append(errors, fmt.Errorf("Genfig: could not parse float64 from CONFIG_RANDOMIZER_THRESHOLD ('%s')\n", val))
+	if src, val, exists, err := lookupEnv("randomizer.threshold", "RANDOMIZER_THRESHOLD"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
+		if err = parseFloat64(val, &c.Randomizer.Threshold); err != nil {
+			errs = append(errs, envError(src, val, "float64", err))
+		} else {
+			recordSource(c, "randomizer.threshold", src)
 		}
 	}
 
-	envs = []string{"secrets", "SECRETS"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
-		if err := parseStringSlice(val, &c.Secrets); err != nil {
-			errors = append(errors, fmt.Errorf("Genfig: could not parse []string from CONFIG_SECRETS ('%s')\n", val))
+	if src, val, exists, err := lookupEnv("secrets", "SECRETS"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
+		if err = parseStringSlice(val, &c.Secrets); err != nil {
+			errs = append(errs, envError(src, val, "[]string", err))
+		} else {
+			recordSource(c, "secrets", src)
 		}
 	}
 
-	envs = []string{"server.host", "SERVER_HOST"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists {
+	if src, val, exists, err := lookupEnv("server.host", "SERVER_HOST"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
 		c.Server.Host = val
+		recordSource(c, "server.host", src)
 	}
 
-	envs = []string{"server.port", "SERVER_PORT"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
+	if src, val, exists, err := lookupEnv("server.port", "SERVER_PORT"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
+		if err = parseInt64(val, &c.Server.Port); err != nil {
+			errs = append(errs, envError(src, val, "int64", err))
+		} else {
+			recordSource(c, "server.port", src)
 		}
 	}
-	if exists {
-		if err := parseInt64(val, &c.Server.Port); err != nil {
-			errors = append(errors, fmt.Errorf("Genfig: could not parse int64 from CONFIG_SERVER_PORT ('%s')\n", val))
-		}
+
+	if src, val, exists, err := lookupEnv("version", "VERSION"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
+		c.Version = val
+		recordSource(c, "version", src)
 	}
 
-	envs = []string{"version", "VERSION"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
+	if src, val, exists, err := lookupEnv("wip", "WIP"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists {
+		if err = parseBool(val, &c.Wip); err != nil {
+			errs = append(errs, envError(src, val, "bool", err))
+		} else {
+			recordSource(c, "wip", src)
 		}
 	}
-	if exists {
-		c.Version = val
+
+	if len(errs) == 0 {
+		return nil
 	}
+	return errs
+}
 
-	envs = []string{"wip", "WIP"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
+// lookupEnv returns the value of the first of the given env vars, which is set.
+// Otherwise, the value is read from the file given by <NAME>_FILE or from the
+// file named like the env var in the secrets dir. The source describes, where
+// the value was found
+func lookupEnv(names ...string) (src string, val string, exists bool, err error) {
+	for _, name := range names {
+		if val, exists = os.LookupEnv(name); exists {
+			return "env var " + name, val, true, nil
 		}
 	}
-	if exists {
-		if err := parseBool(val, &c.Wip); err != nil {
-			errors = append(errors, fmt.Errorf("Genfig: could not parse bool from CONFIG_WIP ('%s')\n", val))
+	for _, name := range names {
+		if file, set := os.LookupEnv(name + "_FILE"); set {
+			val, err = readSecret(file)
+			return fmt.Sprintf("file '%s' (%s_FILE)", file, name), val, err == nil, err
 		}
 	}
-
-	if len(errors) == 0 {
-		return nil
-	} else {
-		return errors
+	if secretsDir == "" {
+		return "", "", false, nil
+	}
+	for _, name := range names {
+		file := filepath.Join(secretsDir, name)
+		if _, statErr := os.Stat(file); os.IsNotExist(statErr) {
+			continue
+		}
+		val, err = readSecret(file)
+		return fmt.Sprintf("file '%s'", file), val, err == nil, err
 	}
+	return "", "", false, nil
 }
 
-// these are wrappers, so that they can
-// a) be referenced easily be the code generator and
-// b) be replaces easily by you (or me)
-func parseInt64(s string, i *int64) (err error) {
-	if got, err := strconv.ParseInt(s, 10, 0); err == nil {
-		*i = got
+// readSecret returns the content of file without leading and trailing white space
+func readSecret(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
 	}
-	return
+	return strings.TrimSpace(string(data)), nil
 }
 
-func parseFloat64(s string, f *float64) (err error) {
-	if got, err := strconv.ParseFloat(s, 0); err == nil {
-		*f = got
+// envError describes a value found in src, which could not be parsed into typ
+func envError(src string, val string, typ string, err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		err = numErr.Err
 	}
-	return
+	return fmt.Errorf("invalid value '%s' of %s, expected %s: %v", val, src, typ, err)
 }
 
-func parseBool(s string, b *bool) (err error) {
-	if got, err := strconv.ParseBool(s); err == nil {
-		*b = got
+// these are wrappers, so that they can
+// a) be referenced easily be the code generator and
+// b) be replaces easily by you (or me).
+// Integers are parsed like Go literals, e.g. '0x1f', '0o17' or '1_000'
+
+func parseInt64(s string, i *int64) error {
+	got, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return err
 	}
-	return
+	*i = int64(got)
+	return nil
 }
 
-func parseStringSlice(s string, a *[]string) (err error) {
-	add := false
-	if strings.HasPrefix(s, "+") {
-		add = true
-		s = s[1:]
-	}
-	tmp := []string{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
+func parseFloat64(s string, f *float64) error {
+	got, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
 	}
-	if add {
-		*a = append(*a, tmp...)
-	} else {
-		*a = tmp
-	}
-	return
+	*f = float64(got)
+	return nil
 }
 
-func parseInt64Slice(s string, a *[]int64) (err error) {
-	add := false
-	if strings.HasPrefix(s, "+") {
-		add = true
-		s = s[1:]
+func parseBool(s string, b *bool) error {
+	got, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
 	}
-	tmp := []int64{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
-	}
-	if add {
-		*a = append(*a, tmp...)
-	} else {
-		*a = tmp
-	}
-	return
+	*b = got
+	return nil
 }
 
-func parseFloat64Slice(s string, a *[]float64) (err error) {
+// Slices are parsed as JSON arrays, a leading '+' appends to the current slice
+
+func parseStringSlice(s string, a *[]string) error {
 	add := false
 	if strings.HasPrefix(s, "+") {
 		add = true
 		s = s[1:]
 	}
-	tmp := []float64{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
+	tmp := []string{}
+	if err := json.Unmarshal([]byte(s), &tmp); err != nil {
+		return err
 	}
 	if add {
 		*a = append(*a, tmp...)
 	} else {
 		*a = tmp
 	}
-	return
+	return nil
 }
 
-func parseInterfaceSlice(s string, a *[]interface{}) (err error) {
+func parseInterfaceSlice(s string, a *[]interface{}) error {
 	add := false
 	if strings.HasPrefix(s, "+") {
 		add = true
 		s = s[1:]
 	}
 	tmp := []interface{}{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
+	if err := json.Unmarshal([]byte(s), &tmp); err != nil {
+		return err
 	}
 	if add {
 		*a = append(*a, tmp...)
 	} else {
 		*a = tmp
 	}
-	return
+	return nil
 }
 
-func parseMapSlice(s string, a *[]map[string]interface{}) (err error) {
+func parseMapSlice(s string, a *[]map[string]interface{}) error {
 	add := false
 	if strings.HasPrefix(s, "+") {
 		add = true
 		s = s[1:]
 	}
 	tmp := []map[string]interface{}{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
+	if err := json.Unmarshal([]byte(s), &tmp); err != nil {
+		return err
 	}
 	if add {
 		*a = append(*a, tmp...)
 	} else {
 		*a = tmp
 	}
-	return
+	return nil
 }
@@ -9,18 +9,14 @@ import (
 	"os"
 )
 
-var (
-	_ = os.Setenv
-	_ = fmt.Sprintf
-	_ = json.Marshal
-)
-
 func (c *Config) WriteToEnv() {
 	var buf []byte
 	_ = buf
 
 	_ = os.Setenv("APIS_GOOGLE_URI", fmt.Sprintf("%v", c.Apis.Google.Uri))
 
+	_ = os.Setenv("DB_DB_NAME", fmt.Sprintf("%v", c.Db.Db_name))
+
 	_ = os.Setenv("DB_PASS", fmt.Sprintf("%v", c.Db.Pass))
 
 	_ = os.Setenv("DB_URI", fmt.Sprintf("%v", c.Db.Uri))
@@ -54,13 +50,16 @@ func (c *Config) WriteToEnv() {
 
 }
 
+// PrintDebugEnvs prints the env vars WriteToEnv sets, with the values of secret fields redacted
 func (c *Config) PrintDebugEnvs(w io.Writer) {
 	var buf []byte
 	_ = buf
 
 	fmt.Fprintf(w, "APIS_GOOGLE_URI/apis.google.uri='%v'\n", c.Apis.Google.Uri)
 
-	fmt.Fprintf(w, "DB_PASS/db.pass='%v'\n", c.Db.Pass)
+	fmt.Fprintf(w, "DB_DB_NAME/db.db_name='%v'\n", c.Db.Db_name)
+
+	fmt.Fprintf(w, "DB_PASS/db.pass='%v'\n", "[redacted]")
 
 	fmt.Fprintf(w, "DB_URI/db.uri='%v'\n", c.Db.Uri)
 
@@ -80,8 +79,7 @@ func (c *Config) PrintDebugEnvs(w io.Writer) {
 
 	fmt.Fprintf(w, "RANDOMIZER_THRESHOLD/randomizer.threshold='%v'\n", c.Randomizer.Threshold)
 
-	buf, _ = json.Marshal(c.Secrets)
-	fmt.Fprintf(w, "SECRETS/secrets='%v'\n", string(buf))
+	fmt.Fprintf(w, "SECRETS/secrets='%v'\n", "[redacted]")
 
 	fmt.Fprintf(w, "SERVER_HOST/server.host='%v'\n", c.Server.Host)
 
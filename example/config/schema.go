@@ -3,17 +3,18 @@
 package config
 
 type Config struct {
-	Apis       ConfigApis
-	Db         ConfigDb
-	EmptyArray []interface{}
-	List       []map[string]interface{}
-	LongDesc   ConfigLongDesc
-	Project    string
-	Randomizer ConfigRandomizer
-	Secrets    []string
-	Server     ConfigServer
-	Version    string
-	Wip        bool
+	Apis             ConfigApis
+	Db               ConfigDb
+	EmptyArray       []interface{}
+	List             []map[string]interface{}
+	LongDesc         ConfigLongDesc
+	Project          string
+	Randomizer       ConfigRandomizer
+	Secrets          []string
+	Server           ConfigServer
+	Version          string
+	Wip              bool
+	lastSubstitution *substitutionState
 }
 
 type ConfigApis struct {
@@ -25,9 +26,10 @@ type ConfigApisGoogle struct {
 }
 
 type ConfigDb struct {
-	Pass string
-	Uri  string
-	User string
+	Db_name string
+	Pass    string
+	Uri     string
+	User    string
 }
 
 type ConfigLongDesc struct {
@@ -1,5 +1,5 @@
 //go:generate rm -rf config
-//go:generate go run ../ --dir config ../fixtures/configs/default.yml ../fixtures/configs/*.yaml ../fixtures/configs/*.json ../fixtures/configs/*.toml ../fixtures/configs/.env*
+//go:generate go run ../ --dir config --docs CONFIG.md ../fixtures/configs/default.yml ../fixtures/configs/*.yaml ../fixtures/configs/*.json ../fixtures/configs/*.toml ../fixtures/configs/.env*
 
 package main
 
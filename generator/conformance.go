@@ -0,0 +1,189 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/plugins"
+	"github.com/thlcodes/genfig/writers"
+)
+
+const (
+	structType = "struct"
+)
+
+var (
+	// referenceTypes are the types of non-string fields, which can hold references
+	referenceTypes = map[string]bool{"int64": true, "uint64": true, "float64": true, "bool": true}
+)
+
+// Nonconformity describes a single field of an environment config,
+// which does not conform to the schema of the default config.
+// An empty Expected means, that the field is not defined in the default config,
+// an empty Actual, that the field of the default config is missing.
+type Nonconformity struct {
+	models.Position
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (n Nonconformity) String() string {
+	pos := "-"
+	if n.Line > 0 {
+		pos = fmt.Sprintf("%d:%d", n.Line, n.Column)
+	}
+	if n.Document > 0 {
+		pos = fmt.Sprintf("document %d, %s", n.Document, pos)
+	}
+	if n.Expected == "" {
+		return fmt.Sprintf("%s '%s': not defined in default config (%s)", pos, n.Path, n.Actual)
+	}
+	if n.Actual == "" {
+		return fmt.Sprintf("%s '%s': missing, defined in default config (%s)", pos, n.Path, n.Expected)
+	}
+	return fmt.Sprintf("%s '%s': expected %s, got %s", pos, n.Path, n.Expected, n.Actual)
+}
+
+// ConformanceError holds all nonconformities found in all environment configs
+type ConformanceError struct {
+	Nonconformities []Nonconformity
+}
+
+// Error returns all nonconformities grouped by file
+func (e *ConformanceError) Error() string {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "%d non-conformant field(s) found:", len(e.Nonconformities))
+	_ = e.Report(buf)
+	return strings.TrimRight(buf.String(), "\n")
+}
+
+// Files returns all files with at least one nonconformity
+func (e *ConformanceError) Files() []string {
+	files := []string{}
+	seen := map[string]bool{}
+	for _, n := range e.Nonconformities {
+		if !seen[n.File] {
+			seen[n.File] = true
+			files = append(files, n.File)
+		}
+	}
+	return files
+}
+
+// Report writes all nonconformities grouped by file into w
+func (e *ConformanceError) Report(w io.Writer) (err error) {
+	for _, f := range e.Files() {
+		if _, err = fmt.Fprintf(w, "\n%s\n", f); err != nil {
+			return
+		}
+		for _, n := range e.Nonconformities {
+			if n.File != f {
+				continue
+			}
+			if _, err = fmt.Fprintf(w, "\t%s\n", n); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// checkConformance checks all fields of config against the default schema
+// and returns all nonconformities sorted by their position. If references are
+// allowed, non-string fields may consist of a single reference to be substituted,
+// e.g. 'port: ${PORT}'. If strict, fields of the default config missing in config
+// are reported as well, otherwise they are taken from the default config
+func checkConformance(src models.Position, config map[string]interface{}, positions models.PositionMap, schema models.SchemaMap, references bool, strict bool) []Nonconformity {
+	found := []Nonconformity{}
+	walked := map[string]bool{"": true}
+	walkConformance(src, "Config", "", config, positions, schema, references, walked, &found)
+	if strict {
+		found = append(found, missingFields(src, schema, walked)...)
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		if found[i].Line != found[j].Line {
+			return found[i].Line < found[j].Line
+		}
+		return found[i].Column < found[j].Column
+	})
+	return found
+}
+
+// walkConformance checks all fields of m, whose schema name is p, and adds the lowercase
+// dotted paths of all fields found in the schema to walked, true for the walked structs
+func walkConformance(src models.Position, p string, path string, m map[string]interface{}, positions models.PositionMap, schema models.SchemaMap, references bool, walked map[string]bool, found *[]Nonconformity) {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := m[k]
+		// build the schema name the same way writers.WriteSchema does
+		n := strings.Title(p + "_" + strings.Title(k))
+		kp := path + k
+		if path != "" {
+			kp = path + "." + k
+		}
+		pos, known := positions[kp]
+		if !known || pos.File == "" {
+			// keys of included files already hold their own file
+			pos.File, pos.Document = src.File, src.Document
+		}
+
+		actual := structType
+		sub, isMap := v.(map[string]interface{})
+		if !isMap {
+			buf := &bytes.Buffer{}
+			writers.WriteSchemaType(buf, n, v, models.SchemaMap{}, 0)
+			actual = buf.String()
+		}
+
+		s, exists := schema[strings.Replace(n, "_", "", -1)]
+		expected := s.Content
+		if s.IsStruct {
+			expected = structType
+		}
+		if exists {
+			walked[strings.ToLower(kp)] = isMap && expected == actual
+		}
+		switch {
+		case !exists:
+			*found = append(*found, Nonconformity{Position: pos, Path: kp, Actual: actual})
+		case references && referenceTypes[expected] && plugins.IsReference(v):
+		case expected != actual:
+			*found = append(*found, Nonconformity{Position: pos, Path: kp, Expected: expected, Actual: actual})
+		case isMap:
+			walkConformance(src, n, kp, sub, positions, schema, references, walked, found)
+		}
+	}
+}
+
+// missingFields returns the fields of the schema, which were not walked, although their
+// parent was, sorted by their paths. Fields within missing structs are not reported
+func missingFields(src models.Position, schema models.SchemaMap, walked map[string]bool) []Nonconformity {
+	missing := []Nonconformity{}
+	for _, s := range schema {
+		keys := s.Segments()
+		if len(keys) == 0 || !walked[strings.ToLower(strings.Join(keys[:len(keys)-1], "."))] {
+			continue
+		}
+		if _, found := walked[s.DotPath()]; found {
+			continue
+		}
+		expected := s.Content
+		if s.IsStruct {
+			expected = structType
+		}
+		missing = append(missing, Nonconformity{Position: models.Position{File: src.File, Document: src.Document}, Path: strings.Join(keys, "."), Expected: expected})
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Path < missing[j].Path
+	})
+	return missing
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thlcodes/genfig/models"
+)
+
+func Test_checkConformance(t *testing.T) {
+	schema := models.SchemaMap{
+		"Config":       models.Schema{IsStruct: true, Content: "struct {}"},
+		"ConfigA":      models.Schema{Content: "string"},
+		"ConfigB":      models.Schema{IsStruct: true, Content: "struct {}"},
+		"ConfigBC":     models.Schema{Content: "int64"},
+		"ConfigLongD":  models.Schema{IsStruct: true, Content: "struct {}"},
+		"ConfigLongDE": models.Schema{Content: "[]string"},
+	}
+	positions := models.PositionMap{"a": {Line: 1, Column: 1}, "b.c": {Line: 3, Column: 3}}
+	tests := []struct {
+		name       string
+		config     map[string]interface{}
+		references bool
+		want       []Nonconformity
+	}{
+		{"empty", map[string]interface{}{}, false, []Nonconformity{}},
+		{"conformant", map[string]interface{}{"a": "", "b": map[string]interface{}{"c": int64(1)}, "longD": map[string]interface{}{"e": []interface{}{"x"}}}, false, []Nonconformity{}},
+		{"type mismatch", map[string]interface{}{"a": 1}, false, []Nonconformity{
+			{Position: models.Position{File: "f", Line: 1, Column: 1}, Path: "a", Expected: "string", Actual: "int64"},
+		}},
+		{"unknown field", map[string]interface{}{"x": 1}, false, []Nonconformity{
+			{Position: models.Position{File: "f"}, Path: "x", Actual: "int64"},
+		}},
+		{"map instead of basic", map[string]interface{}{"a": map[string]interface{}{"x": 1}}, false, []Nonconformity{
+			{Position: models.Position{File: "f", Line: 1, Column: 1}, Path: "a", Expected: "string", Actual: "struct"},
+		}},
+		{"basic instead of map", map[string]interface{}{"b": ""}, false, []Nonconformity{
+			{Position: models.Position{File: "f"}, Path: "b", Expected: "struct", Actual: "string"},
+		}},
+		{"multiple, sorted by position", map[string]interface{}{"b": map[string]interface{}{"c": "", "d": true}, "a": 1}, false, []Nonconformity{
+			{Position: models.Position{File: "f"}, Path: "b.d", Actual: "bool"},
+			{Position: models.Position{File: "f", Line: 1, Column: 1}, Path: "a", Expected: "string", Actual: "int64"},
+			{Position: models.Position{File: "f", Line: 3, Column: 3}, Path: "b.c", Expected: "int64", Actual: "string"},
+		}},
+		{"reference", map[string]interface{}{"b": map[string]interface{}{"c": "${C:-1}"}}, true, []Nonconformity{}},
+		{"reference not allowed", map[string]interface{}{"b": map[string]interface{}{"c": "${C}"}}, false, []Nonconformity{
+			{Position: models.Position{File: "f", Line: 3, Column: 3}, Path: "b.c", Expected: "int64", Actual: "string"},
+		}},
+		{"no reference", map[string]interface{}{"b": map[string]interface{}{"c": "a ${C}"}}, true, []Nonconformity{
+			{Position: models.Position{File: "f", Line: 3, Column: 3}, Path: "b.c", Expected: "int64", Actual: "string"},
+		}},
+		{"reference for slice", map[string]interface{}{"longD": map[string]interface{}{"e": "${E}"}}, true, []Nonconformity{
+			{Position: models.Position{File: "f"}, Path: "longD.e", Expected: "[]string", Actual: "string"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, checkConformance(models.Position{File: "f"}, tt.config, positions, schema, tt.references, false))
+		})
+	}
+}
+
+func Test_checkConformance_Strict(t *testing.T) {
+	schema := models.SchemaMap{
+		"Config":       models.Schema{IsStruct: true, Path: "Config"},
+		"ConfigA":      models.Schema{Content: "string", Path: "Config_A", Keys: []string{"a"}},
+		"ConfigB":      models.Schema{IsStruct: true, Path: "Config_B", Keys: []string{"b"}},
+		"ConfigBC":     models.Schema{Content: "int64", Path: "Config_B_C", Keys: []string{"b", "c"}},
+		"ConfigBD":     models.Schema{Content: "bool", Path: "Config_B_D", Keys: []string{"b", "d"}},
+		"ConfigLongD":  models.Schema{IsStruct: true, Path: "Config_LongD", Keys: []string{"longD"}},
+		"ConfigLongDE": models.Schema{Content: "[]string", Path: "Config_LongD_E", Keys: []string{"longD", "e"}},
+	}
+	src := models.Position{File: "f", Document: 2}
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   []Nonconformity
+	}{
+		{"complete", map[string]interface{}{"a": "", "b": map[string]interface{}{"c": int64(1), "d": true}, "longD": map[string]interface{}{"e": []interface{}{"x"}}}, []Nonconformity{}},
+		{"missing struct", map[string]interface{}{"a": "", "b": map[string]interface{}{"c": int64(1), "d": true}}, []Nonconformity{
+			{Position: src, Path: "longD", Expected: "struct"},
+		}},
+		{"missing fields", map[string]interface{}{"b": map[string]interface{}{"c": int64(1)}, "longD": map[string]interface{}{}}, []Nonconformity{
+			{Position: src, Path: "a", Expected: "string"},
+			{Position: src, Path: "b.d", Expected: "bool"},
+			{Position: src, Path: "longD.e", Expected: "[]string"},
+		}},
+		{"mismatched struct", map[string]interface{}{"a": "", "b": "", "longD": map[string]interface{}{"e": []interface{}{"x"}}}, []Nonconformity{
+			{Position: src, Path: "b", Expected: "struct", Actual: "string"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, checkConformance(src, tt.config, models.PositionMap{}, schema, false, true))
+		})
+	}
+	assert.Equal(t, "- 'a': missing, defined in default config (string)", Nonconformity{Path: "a", Expected: "string"}.String())
+}
+
+func Test_ConformanceError(t *testing.T) {
+	err := &ConformanceError{Nonconformities: []Nonconformity{
+		{Position: models.Position{File: "a.yml", Line: 1, Column: 2}, Path: "a", Expected: "string", Actual: "int64"},
+		{Position: models.Position{File: "b.yml"}, Path: "b", Actual: "bool"},
+		{Position: models.Position{File: "a.yml", Line: 3, Column: 1}, Path: "c.d", Expected: "struct", Actual: "string"},
+	}}
+	assert.Equal(t, []string{"a.yml", "b.yml"}, err.Files())
+	assert.Equal(t, strings.Join([]string{
+		"3 non-conformant field(s) found:",
+		"a.yml",
+		"\t1:2 'a': expected string, got int64",
+		"\t3:1 'c.d': expected struct, got string",
+		"",
+		"b.yml",
+		"\t- 'b': not defined in default config (bool)",
+	}, "\n"), err.Error())
+}
+
+func Test_Generate_Conformance(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	configsDir := fixturesDir + "/configs"
+	_, err := Generate([]string{configsDir + "/default.yml", configsDir + "/nonconformant.yml", configsDir + "/nonconformant.many.yml"}, models.Params{Dir: tmpDir})
+	require.Error(t, err)
+	cerr, ok := err.(*ConformanceError)
+	require.True(t, ok)
+	assert.Equal(t, []string{configsDir + "/nonconformant.yml", configsDir + "/nonconformant.many.yml"}, cerr.Files())
+	assert.Equal(t, []Nonconformity{
+		{Position: models.Position{File: configsDir + "/nonconformant.yml", Line: 1, Column: 1}, Path: "version", Expected: "string", Actual: "int64"},
+		{Position: models.Position{File: configsDir + "/nonconformant.many.yml", Line: 1, Column: 1}, Path: "version", Expected: "string", Actual: "int64"},
+		{Position: models.Position{File: configsDir + "/nonconformant.many.yml", Line: 3, Column: 3}, Path: "server.port", Expected: "int64", Actual: "string"},
+		{Position: models.Position{File: configsDir + "/nonconformant.many.yml", Line: 4, Column: 3}, Path: "server.tls", Actual: "bool"},
+		{Position: models.Position{File: configsDir + "/nonconformant.many.yml", Line: 5, Column: 1}, Path: "db", Expected: "struct", Actual: "string"},
+		{Position: models.Position{File: configsDir + "/nonconformant.many.yml", Line: 8, Column: 5}, Path: "apis.google.uri", Expected: "string", Actual: "struct"},
+	}, cerr.Nonconformities)
+}
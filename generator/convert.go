@@ -0,0 +1,587 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/parsers"
+	"github.com/thlcodes/genfig/util"
+)
+
+var (
+	// convertTargets are the file types config files can be converted into
+	convertTargets = []string{"yml", "json", "toml", "dotenv"}
+	// tomlBareKeyRe matches toml keys, which do not need to be quoted
+	tomlBareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	// dotenvKeyRe matches the keys, which can be represented in .env files
+	dotenvKeyRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+)
+
+// converter writes a parsed config in another format
+type converter struct {
+	positions models.PositionMap
+	buf       bytes.Buffer
+}
+
+// Convert converts the config file in into the format of the file out, given by its
+// extension like for all config files, e.g. 'production.json' into 'production.yml'.
+// The keys keep the order of in and the comments documenting them are kept, if both
+// formats support comments (i.e. all but json). Include directives are converted as they are.
+// Values, which cannot be represented by the format of out (e.g. null in toml or arrays
+// mixing types in toml), are reported as error and out is not written
+func Convert(in string, out string) error {
+	from, to := parsers.FileType(in), parsers.FileType(out)
+	s, found := parsers.Strategies[from]
+	if !found {
+		return fmt.Errorf("%s: Unsupported file type '%s'", in, from)
+	}
+	supported := false
+	for _, t := range convertTargets {
+		supported = supported || t == to
+	}
+	if !supported {
+		return fmt.Errorf("%s: Unsupported file type '%s', expected one of '%s'", out, to, strings.Join(convertTargets, "', '"))
+	}
+	docs, err := parseFile(in, s)
+	if err != nil {
+		return fileError(in, err)
+	}
+	if len(docs) > 1 {
+		return fmt.Errorf("%s: Multi-document files cannot be converted", in)
+	}
+	data, err := convert(docs[0].Data, docs[0].Positions, to)
+	if err != nil {
+		return fileError(in, err)
+	}
+	return ioutil.WriteFile(out, data, 0666)
+}
+
+// convert writes the config m, whose keys are positioned at positions, as file of type to
+func convert(m map[string]interface{}, positions models.PositionMap, to string) ([]byte, error) {
+	c := &converter{positions: positions}
+	normalized, err := c.normalize("", m)
+	if err != nil {
+		return nil, err
+	}
+	m = normalized.(map[string]interface{})
+	switch to {
+	case "yml":
+		err = c.writeYAML(m)
+	case "json":
+		if err = c.writeJSON("", m, ""); err == nil {
+			c.buf.WriteString("\n")
+		}
+	case "toml":
+		err = c.writeTOML("", "", m)
+	case "dotenv":
+		err = c.writeDotenv("", "", m)
+	default:
+		err = fmt.Errorf("Unsupported file type '%s'", to)
+	}
+	return c.buf.Bytes(), err
+}
+
+// normalize returns v with all integers as int64 and all slices as []interface{},
+// failing on values of unsupported types
+func (c *converter) normalize(path string, v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, string, bool, int64, float64, time.Time:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, sub := range v {
+			var err error
+			if m[k], err = c.normalize(parsers.JoinPath(path, k), sub); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case []map[string]interface{}:
+		s := make([]interface{}, len(v))
+		for i, sub := range v {
+			s[i] = sub
+		}
+		return c.normalize(path, s)
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, sub := range v {
+			var err error
+			if s[i], err = c.normalize(fmt.Sprintf("%s[%d]", path, i), sub); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	}
+	return nil, c.errorf(path, "Value of type %T is not supported", v)
+}
+
+// keys returns the keys of the map at path in the order of the source, keys without position last
+func (c *converter) keys(path string, m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		pi, foundi := c.positions[parsers.JoinPath(path, keys[i])]
+		pj, foundj := c.positions[parsers.JoinPath(path, keys[j])]
+		switch {
+		case foundi != foundj:
+			return foundi
+		case pi.Line != pj.Line:
+			return pi.Line < pj.Line
+		case pi.Column != pj.Column:
+			return pi.Column < pj.Column
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// doc returns the comment documenting the key at path
+func (c *converter) doc(path string) string {
+	return c.positions[path].Doc
+}
+
+// errorf returns an error at the position of the key at path or of the key containing it
+func (c *converter) errorf(path string, format string, a ...interface{}) error {
+	key := path
+	if i := strings.Index(key, "["); i >= 0 {
+		key = key[:i]
+	}
+	return &parsers.ParseError{Position: c.positions[key], Msg: fmt.Sprintf(format, a...)}
+}
+
+// writeYAML writes m as yaml, with the comments as head comments of the keys
+func (c *converter) writeYAML(m map[string]interface{}) error {
+	root, err := c.yamlNode("", m)
+	if err != nil {
+		return err
+	}
+	enc := yaml.NewEncoder(&c.buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// yamlNode returns the yaml node of the value v at path
+func (c *converter) yamlNode(path string, v interface{}) (*yaml.Node, error) {
+	n := &yaml.Node{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		n.Kind, n.Tag = yaml.MappingNode, "!!map"
+		for _, k := range c.keys(path, v) {
+			key := &yaml.Node{}
+			if err := key.Encode(k); err != nil {
+				return nil, err
+			}
+			if doc := c.doc(parsers.JoinPath(path, k)); doc != "" {
+				key.HeadComment = "# " + doc
+			}
+			value, err := c.yamlNode(parsers.JoinPath(path, k), v[k])
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, key, value)
+		}
+	case []interface{}:
+		n.Kind, n.Tag = yaml.SequenceNode, "!!seq"
+		for i, e := range v {
+			value, err := c.yamlNode(fmt.Sprintf("%s[%d]", path, i), e)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, value)
+		}
+	case float64:
+		n.Kind, n.Tag = yaml.ScalarNode, "!!float"
+		switch {
+		case math.IsNaN(v):
+			n.Value = ".nan"
+		case math.IsInf(v, 1):
+			n.Value = ".inf"
+		case math.IsInf(v, -1):
+			n.Value = "-.inf"
+		default:
+			n.Value = formatFloat(v)
+		}
+	default:
+		if err := n.Encode(v); err != nil {
+			return nil, c.errorf(path, "%v", err)
+		}
+	}
+	return n, nil
+}
+
+// writeJSON writes the value v at path as json, indented by two spaces.
+// Comments are dropped, as json does not support them
+func (c *converter) writeJSON(path string, v interface{}, indent string) error {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			c.buf.WriteString("{}")
+			return nil
+		}
+		c.buf.WriteString("{\n")
+		for i, k := range c.keys(path, v) {
+			if i > 0 {
+				c.buf.WriteString(",\n")
+			}
+			c.buf.WriteString(indent + "  " + jsonString(k) + ": ")
+			if err := c.writeJSON(parsers.JoinPath(path, k), v[k], indent+"  "); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteString("\n" + indent + "}")
+	case []interface{}:
+		if len(v) == 0 {
+			c.buf.WriteString("[]")
+			return nil
+		}
+		c.buf.WriteString("[\n")
+		for i, e := range v {
+			if i > 0 {
+				c.buf.WriteString(",\n")
+			}
+			c.buf.WriteString(indent + "  ")
+			if err := c.writeJSON(fmt.Sprintf("%s[%d]", path, i), e, indent+"  "); err != nil {
+				return err
+			}
+		}
+		c.buf.WriteString("\n" + indent + "]")
+	case string:
+		c.buf.WriteString(jsonString(v))
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return c.errorf(path, "Value of '%s' cannot be represented in json: %v", path, v)
+		}
+		c.buf.WriteString(formatFloat(v))
+	case time.Time:
+		return c.errorf(path, "Value of '%s' cannot be represented in json: datetime %v", path, v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return c.errorf(path, "%v", err)
+		}
+		c.buf.Write(data)
+	}
+	return nil
+}
+
+// writeTOML writes the map m at path as toml table with the given header, its values
+// first, followed by its tables and arrays of tables
+func (c *converter) writeTOML(path string, header string, m map[string]interface{}) error {
+	keys := c.keys(path, m)
+	for _, k := range keys {
+		p := parsers.JoinPath(path, k)
+		if _, isMap := m[k].(map[string]interface{}); isMap || isTableArray(m[k]) {
+			continue
+		}
+		value, err := c.tomlValue(p, m[k])
+		if err != nil {
+			return err
+		}
+		c.comment(p)
+		c.buf.WriteString(tomlKey(k) + " = " + value + "\n")
+	}
+	for _, k := range keys {
+		p, h := parsers.JoinPath(path, k), parsers.JoinPath(header, tomlKey(k))
+		switch v := m[k].(type) {
+		case map[string]interface{}:
+			// tables only holding tables are defined implicitly by their headers
+			if hasValues := len(v) == 0 || c.doc(p) != ""; !hasValues {
+				for _, sub := range v {
+					_, isMap := sub.(map[string]interface{})
+					hasValues = hasValues || !isMap && !isTableArray(sub)
+				}
+				if !hasValues {
+					if err := c.writeTOML(p, h, v); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			c.tomlHeader(p, "["+h+"]")
+			if err := c.writeTOML(p, h, v); err != nil {
+				return err
+			}
+		case []interface{}:
+			if !isTableArray(v) {
+				continue
+			}
+			for i, e := range v {
+				if i == 0 {
+					c.tomlHeader(p, "[["+h+"]]")
+				} else {
+					c.tomlHeader("", "[["+h+"]]")
+				}
+				if err := c.writeTOML(fmt.Sprintf("%s[%d]", p, i), h, e.(map[string]interface{})); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// tomlHeader writes the header of a table, separated by an empty line and documented by the comment of path
+func (c *converter) tomlHeader(path string, header string) {
+	if c.buf.Len() > 0 {
+		c.buf.WriteString("\n")
+	}
+	c.comment(path)
+	c.buf.WriteString(header + "\n")
+}
+
+// tomlValue returns the value v at path as toml value, maps as inline tables
+func (c *converter) tomlValue(path string, v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", c.errorf(path, "Value of '%s' cannot be represented in toml: null", path)
+	case string:
+		return tomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "nan", nil
+		case math.IsInf(v, 1):
+			return "inf", nil
+		case math.IsInf(v, -1):
+			return "-inf", nil
+		}
+		return formatFloat(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case map[string]interface{}:
+		entries := []string{}
+		for _, k := range c.keys(path, v) {
+			value, err := c.tomlValue(parsers.JoinPath(path, k), v[k])
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, tomlKey(k)+" = "+value)
+		}
+		if len(entries) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(entries, ", ") + " }", nil
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, e := range v {
+			if i > 0 && tomlType(e) != tomlType(v[0]) {
+				return "", c.errorf(path, "Array '%s' mixes %s and %s, which cannot be represented in toml", path, tomlType(v[0]), tomlType(e))
+			}
+			var err error
+			if values[i], err = c.tomlValue(fmt.Sprintf("%s[%d]", path, i), e); err != nil {
+				return "", err
+			}
+		}
+		return "[" + strings.Join(values, ", ") + "]", nil
+	}
+	return "", c.errorf(path, "Value of type %T is not supported", v)
+}
+
+// writeDotenv writes the map m at path as lines of a .env file, the names of its keys
+// prefixed by prefix. Since .env files cannot represent empty maps, strings which would be
+// read as other types and strings with leading or trailing spaces or line breaks, these fail
+func (c *converter) writeDotenv(path string, prefix string, m map[string]interface{}) error {
+	for _, k := range c.keys(path, m) {
+		p := parsers.JoinPath(path, k)
+		if path == "" && k == parsers.IncludeKey {
+			if err := c.dotenvInclude(p, m[k]); err != nil {
+				return err
+			}
+			continue
+		}
+		if !dotenvKeyRe.MatchString(k) {
+			return c.errorf(p, "Key '%s' cannot be represented in dotenv, which only supports alphanumeric keys", p)
+		}
+		name := strings.ToUpper(k)
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		if sub, isMap := m[k].(map[string]interface{}); isMap {
+			if len(sub) == 0 {
+				return c.errorf(p, "Empty map '%s' cannot be represented in dotenv", p)
+			}
+			if err := c.writeDotenv(p, name, sub); err != nil {
+				return err
+			}
+			continue
+		}
+		value, err := c.dotenvValue(p, m[k])
+		if err != nil {
+			return err
+		}
+		c.comment(p)
+		c.buf.WriteString(name + "=" + value + "\n")
+	}
+	return nil
+}
+
+// dotenvInclude writes the include directive v as one line per included file
+func (c *converter) dotenvInclude(path string, v interface{}) error {
+	includes, ok := includePaths(v)
+	if !ok {
+		return c.errorf(path, "Invalid %s directive, expected a file or a list of files, got %#v", parsers.IncludeKey, v)
+	}
+	c.comment(path)
+	for _, inc := range includes {
+		c.buf.WriteString(parsers.IncludeKey + "=" + inc + "\n")
+	}
+	return nil
+}
+
+// dotenvValue returns the value v at path as value of a .env file,
+// if it is read as the same value, arrays as json
+func (c *converter) dotenvValue(path string, v interface{}) (string, error) {
+	var value string
+	switch v := v.(type) {
+	case string:
+		value = v
+	case int64:
+		value = strconv.FormatInt(v, 10)
+	case float64:
+		value = formatFloat(v)
+	case bool:
+		value = strconv.FormatBool(v)
+	case []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", c.errorf(path, "Value of '%s' cannot be represented in dotenv: %v", path, err)
+		}
+		value = string(data)
+		// numbers of arrays are read as float64, so only the json has to match
+		if parsed, ok := util.ParseArrayString(value); ok {
+			if reparsed, err := json.Marshal(parsed); err == nil && string(reparsed) == value {
+				return value, nil
+			}
+		}
+		return "", c.errorf(path, "Value of '%s' cannot be represented in dotenv: %s", path, value)
+	default:
+		return "", c.errorf(path, "Value of '%s' cannot be represented in dotenv: %#v", path, v)
+	}
+	if parsed := util.ParseString(strings.TrimSpace(value)); strings.Contains(value, "\n") || !reflect.DeepEqual(parsed, v) {
+		return "", c.errorf(path, "Value of '%s' cannot be represented in dotenv, as %q would be read as %T %#v", path, value, parsed, parsed)
+	}
+	return value, nil
+}
+
+// comment writes the comment documenting the key at path as separate line, if any
+func (c *converter) comment(path string) {
+	if doc := c.doc(path); doc != "" && path != "" {
+		c.buf.WriteString("# " + doc + "\n")
+	}
+}
+
+// isTableArray returns whether v is a non-empty array of maps, i.e. an array of tables in toml
+func isTableArray(v interface{}) bool {
+	s, ok := v.([]interface{})
+	if !ok || len(s) == 0 {
+		return false
+	}
+	for _, e := range s {
+		if _, isMap := e.(map[string]interface{}); !isMap {
+			return false
+		}
+	}
+	return true
+}
+
+// tomlType returns the toml type of the value v, e.g. 'integer'
+func tomlType(v interface{}) string {
+	switch v.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case time.Time:
+		return "datetime"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "table"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// tomlKey returns k as toml key, quoted if it is no bare key
+func tomlKey(k string) string {
+	if tomlBareKeyRe.MatchString(k) {
+		return k
+	}
+	return tomlString(k)
+}
+
+// tomlString returns s as toml basic string
+func tomlString(s string) string {
+	b := &strings.Builder{}
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// jsonString returns s as json string, without escaping html characters
+func jsonString(s string) string {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// formatFloat formats the finite float f, so that it is read as float again, e.g. '1.0'
+func formatFloat(f float64) string {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	s := strconv.FormatFloat(f, format, -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
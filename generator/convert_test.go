@@ -0,0 +1,85 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thlcodes/genfig/parsers"
+)
+
+func Test_Convert(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	const commented = "# the name\nname: app\nratio: 1.0\ndb:\n  # the dsn\n  dsn: postgres://localhost # where to\n  port: 5432\napis:\n  google:\n    uri: google.com\nhosts: [\"a\", \"b\"]\nlist:\n  - b: 2\n    a: 1\n"
+
+	tests := []struct {
+		name    string
+		in      string
+		data    string
+		out     string
+		want    string
+		wantErr string
+	}{
+		{"yaml to toml", "default.yml", commented, "default.toml",
+			"# the name\nname = \"app\"\nratio = 1.0\nhosts = [\"a\", \"b\"]\n\n[db]\n# the dsn\ndsn = \"postgres://localhost\"\nport = 5432\n\n[apis.google]\nuri = \"google.com\"\n\n[[list]]\na = 1\nb = 2\n", ""},
+		{"yaml to json", "default.yml", commented, "default.json",
+			"{\n  \"name\": \"app\",\n  \"ratio\": 1.0,\n  \"db\": {\n    \"dsn\": \"postgres://localhost\",\n    \"port\": 5432\n  },\n  \"apis\": {\n    \"google\": {\n      \"uri\": \"google.com\"\n    }\n  },\n  \"hosts\": [\n    \"a\",\n    \"b\"\n  ],\n  \"list\": [\n    {\n      \"a\": 1,\n      \"b\": 2\n    }\n  ]\n}\n", ""},
+		{"yaml to dotenv", "default.yml", commented, ".env.default",
+			"# the name\nNAME=app\nRATIO=1.0\n# the dsn\nDB_DSN=postgres://localhost\nDB_PORT=5432\nAPIS_GOOGLE_URI=google.com\nHOSTS=[\"a\",\"b\"]\nLIST=[{\"a\":1,\"b\":2}]\n", ""},
+		{"toml to yaml", "default.toml", "# the name\nname = \"app\"\n\n[db] # the db\nport = 5432\n", "default.yml",
+			"# the name\nname: app\n# the db\ndb:\n  port: 5432\n", ""},
+		{"dotenv to yaml", ".env.local", "# the port\nDB_PORT=5432\nDB_HOST=localhost\n$include=./other.env\nNAME=my app\n", "local.yml",
+			"db:\n  # the port\n  port: 5432\n  host: localhost\n$include:\n  - ./other.env\nname: my app\n", ""},
+		{"json to yaml", "production.json", "{\"version\": \"1\", \"db\": {\"uri\": \"x\"}, \"empty\": null}", "production.yml",
+			"version: \"1\"\ndb:\n  uri: x\nempty: null\n", ""},
+		{"mixed array to toml", "default.yml", "name: app\nlist: [1, \"a\"]\n", "default.toml", "", "default.yml:2:1: Array 'list' mixes integer and string, which cannot be represented in toml"},
+		{"null to toml", "default.yml", "name: ~\n", "default.toml", "", "default.yml:1:1: Value of 'name' cannot be represented in toml: null"},
+		{"typed string to dotenv", "default.json", "{\"version\": \"1\"}", ".env.default", "", "default.json:1:2: Value of 'version' cannot be represented in dotenv, as \"1\" would be read as int64 1"},
+		{"key to dotenv", "default.yml", "db:\n  my_key: 1\n", ".env.default", "", "default.yml:2:3: Key 'db.my_key' cannot be represented in dotenv, which only supports alphanumeric keys"},
+		{"nan to json", "default.yml", "ratio: .nan\n", "default.json", "", "default.yml:1:1: Value of 'ratio' cannot be represented in json: NaN"},
+		{"multi-document", "default.yml", "env: a\nname: a\n---\nenv: b\nname: b\n", "default.json", "", "default.yml: Multi-document files cannot be converted"},
+		{"unsupported target", "default.yml", "name: app\n", "default.ini", "", "default.ini: Unsupported file type 'ini', expected one of 'yml', 'json', 'toml', 'dotenv'"},
+		{"parse error", "default.json", "{\"name\": ", "default.yml", "", "default.json:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, _ := ioutil.TempDir(tmpDir, "convert")
+			in, out := filepath.Join(dir, tt.in), filepath.Join(dir, tt.out)
+			_ = ioutil.WriteFile(in, []byte(tt.data), 0666)
+			err := Convert(in, out)
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				_, err := os.Stat(out)
+				assert.True(t, os.IsNotExist(err), "output must not be written")
+				return
+			}
+			require.NoError(t, err)
+			data, err := ioutil.ReadFile(out)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(data))
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		files := []string{"default.yml", "default.toml", "default.json", "default.yaml"}
+		src := filepath.Join(tmpDir, files[0])
+		_ = ioutil.WriteFile(src, []byte(commented), 0666)
+		for i := 1; i < len(files); i++ {
+			require.NoError(t, Convert(filepath.Join(tmpDir, files[i-1]), filepath.Join(tmpDir, files[i])))
+		}
+		want, _ := ioutil.ReadFile(src)
+		got, _ := ioutil.ReadFile(filepath.Join(tmpDir, files[len(files)-1]))
+		wantData, err := parsers.Strategies["yml"].Parse(want)
+		require.NoError(t, err)
+		gotData, err := parsers.Strategies["yml"].Parse(got)
+		require.NoError(t, err)
+		assert.Equal(t, wantData, gotData)
+	})
+}
@@ -0,0 +1,291 @@
+package generator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/plugins"
+	"github.com/thlcodes/genfig/util"
+	"github.com/thlcodes/genfig/writers"
+)
+
+// Formats of the documentation
+const (
+	DocsMarkdown = "markdown"
+	DocsHTML     = "html"
+)
+
+var (
+	docsFuncs = template.FuncMap{
+		"code": func(s string) string {
+			if s == "" {
+				return ""
+			}
+			return "`" + strings.Replace(s, "|", "\\|", -1) + "`"
+		},
+		"codes": func(ss []string) string {
+			codes := make([]string, len(ss))
+			for i, s := range ss {
+				codes[i] = "`" + s + "`"
+			}
+			return strings.Join(codes, ", ")
+		},
+		"cell": func(s string) string {
+			return strings.Replace(strings.Replace(s, "|", "\\|", -1), "\n", " ", -1)
+		},
+	}
+	markdownDocsTpl = template.Must(template.New("markdown").Funcs(docsFuncs).Parse(`# Configuration
+
+Generated by {{.Cmd}} from {{.Sources}}.
+The values of the environments are only listed, if they differ from the default ones ('{{.DefaultEnv}}').
+{{- if .EnvVars}}
+Instead of an env var, a file holding the value can be given by <NAME>_FILE.
+{{- end}}
+{{range .Sections}}
+## {{if .Path}}{{code .Path}}{{else}}Top level{{end}}
+
+| Key | Type |{{if $.EnvVars}} Env vars |{{end}} Default |{{range $.Envs}} {{.}} |{{end}} Description |
+|-----|------|{{if $.EnvVars}}----------|{{end}}---------|{{range $.Envs}}---|{{end}}-------------|
+{{- range .Rows}}
+| {{code .Path}} | {{code .Type}} |{{if $.EnvVars}} {{codes .EnvVars}} |{{end}} {{code .Default}} |{{range .Values}} {{code .}} |{{end}} {{cell .Doc}} |
+{{- end}}
+{{end -}}
+`))
+	htmlDocsTpl = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Configuration</title>
+</head>
+<body>
+<h1>Configuration</h1>
+<p>
+Generated by {{.Cmd}} from {{.Sources}}.
+The values of the environments are only listed, if they differ from the default ones ('{{.DefaultEnv}}').
+{{- if .EnvVars}}
+Instead of an env var, a file holding the value can be given by &lt;NAME&gt;_FILE.
+{{- end}}
+</p>
+{{- range .Sections}}
+<h2>{{if .Path}}<code>{{.Path}}</code>{{else}}Top level{{end}}</h2>
+<table>
+<thead>
+<tr><th>Key</th><th>Type</th>{{if $.EnvVars}}<th>Env vars</th>{{end}}<th>Default</th>{{range $.Envs}}<th>{{.}}</th>{{end}}<th>Description</th></tr>
+</thead>
+<tbody>
+{{- range .Rows}}
+<tr><td><code>{{.Path}}</code></td><td><code>{{.Type}}</code></td>{{if $.EnvVars}}<td>{{range $i, $name := .EnvVars}}{{if $i}}, {{end}}<code>{{$name}}</code>{{end}}</td>{{end}}<td>{{if .Default}}<code>{{.Default}}</code>{{end}}</td>{{range .Values}}<td>{{if .}}<code>{{.}}</code>{{end}}</td>{{end}}<td>{{.Doc}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+</body>
+</html>
+`))
+)
+
+// docs is the documentation of a config
+type docs struct {
+	Cmd string
+	// Sources lists the config files, e.g. "'default.yml', 'production.json'"
+	Sources    string
+	DefaultEnv string
+	// Envs are the names of all other envs
+	Envs []string
+	// EnvVars is true, if the fields can be overridden by env vars
+	EnvVars  bool
+	Sections []docsSection
+}
+
+// docsSection documents the fields of a struct of the config
+type docsSection struct {
+	// Path is the dotted path of the struct, e.g. 'db', empty for the top level
+	Path string
+	Rows []docsRow
+}
+
+// docsRow documents a field of the config
+type docsRow struct {
+	Path    string
+	Type    string
+	EnvVars []string
+	Default string
+	// Values are the values of the other envs, empty if equal to the default value
+	Values []string
+	Doc    string
+}
+
+// DocsFormat returns the format of the documentation file f, i.e. DocsHTML for '*.html'
+// and '*.htm' files, DocsMarkdown for all others
+func DocsFormat(f string) string {
+	switch strings.ToLower(filepath.Ext(f)) {
+	case ".html", ".htm":
+		return DocsHTML
+	}
+	return DocsMarkdown
+}
+
+// WriteDocs writes the documentation of the config given by files in the format
+// DocsMarkdown or DocsHTML to w, see Params.Docs
+func WriteDocs(w io.Writer, files []string, params models.Params, format string) error {
+	if len(files) == 0 {
+		return errors.New("No files to document")
+	}
+	if params.DefaultEnv == "" {
+		params.DefaultEnv = defaultEnvName
+	}
+	l, schema, selectedPlugins, err := loadWithSchema(files, params)
+	if err != nil {
+		return err
+	}
+	return writeDocs(w, l, params.DefaultEnv, schema, selectedPlugins, format)
+}
+
+// loadWithSchema loads the envs of files, selects and configures the plugins and returns
+// the schema of the default env including the docs of its keys, like Generate does
+func loadWithSchema(files []string, params models.Params) (*loadedEnvs, models.SchemaMap, []plugins.Plugin, error) {
+	l, err := loadEnvs(files, params.DefaultEnv)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	selectedPlugins, err := selectPlugins(params)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	schema, err := writers.WriteAndReturnSchema(util.NoopWriter{}, l.data[params.DefaultEnv])
+	if err != nil {
+		return nil, nil, nil, fileError(l.sources[params.DefaultEnv].File, err)
+	}
+	schemaDocs(schema, l.positions[params.DefaultEnv])
+	return l, schema, selectedPlugins, nil
+}
+
+// writeDocsFile writes the documentation to the file f in the format given by its extension
+func writeDocsFile(f string, l *loadedEnvs, defaultEnv string, schema models.SchemaMap, selectedPlugins []plugins.Plugin) (err error) {
+	file, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return writeDocs(file, l, defaultEnv, schema, selectedPlugins, DocsFormat(f))
+}
+
+// writeDocs writes the documentation of the loaded envs in the given format to w.
+// Env vars are documented, if the plugin 'update_from_env' is selected
+func writeDocs(w io.Writer, l *loadedEnvs, defaultEnv string, schema models.SchemaMap, selectedPlugins []plugins.Plugin, format string) error {
+	d := docs{Cmd: defaultCmd, DefaultEnv: defaultEnv}
+	for _, p := range selectedPlugins {
+		d.EnvVars = d.EnvVars || p.Name() == "update_from_env"
+	}
+	sources := []string{}
+	for env := range l.data {
+		if env != defaultEnv {
+			d.Envs = append(d.Envs, env)
+		}
+		sources = append(sources, "'"+sourceName(l.sources[env])+"'")
+	}
+	sort.Strings(d.Envs)
+	sort.Strings(sources)
+	d.Sources = strings.Join(sources, ", ")
+
+	defaults := map[string]interface{}{}
+	flatten("", l.data[defaultEnv], defaults)
+	values := make([]map[string]interface{}, len(d.Envs))
+	for i, env := range d.Envs {
+		merged, err := writers.MergeConfig(l.data[defaultEnv], l.data[env])
+		if err != nil {
+			return fileError(l.sources[env].File, err)
+		}
+		values[i] = map[string]interface{}{}
+		flatten("", merged, values[i])
+	}
+
+	sections := map[string]*docsSection{}
+	for _, s := range schema {
+		if s.IsStruct {
+			continue
+		}
+		path := s.DotPath()
+		section := ""
+		if i := strings.LastIndex(path, "."); i >= 0 {
+			section = path[:i]
+		}
+		if sections[section] == nil {
+			sections[section] = &docsSection{Path: section}
+		}
+		secret := plugins.IsSecret(s)
+		row := docsRow{
+			Path:    path,
+			Type:    s.Content,
+			Default: docsValue(defaults[path], secret),
+			Doc:     strings.TrimSpace(strings.Replace(s.Doc, plugins.SecretMarker, "", -1)),
+		}
+		if d.EnvVars {
+			row.EnvVars = plugins.EnvNames(s)
+		}
+		for i := range d.Envs {
+			value := ""
+			if docsValue(values[i][path], false) != docsValue(defaults[path], false) {
+				value = docsValue(values[i][path], secret)
+			}
+			row.Values = append(row.Values, value)
+		}
+		sections[section].Rows = append(sections[section].Rows, row)
+	}
+	for _, s := range sections {
+		sort.Slice(s.Rows, func(i, j int) bool { return s.Rows[i].Path < s.Rows[j].Path })
+		d.Sections = append(d.Sections, *s)
+	}
+	sort.Slice(d.Sections, func(i, j int) bool { return d.Sections[i].Path < d.Sections[j].Path })
+
+	switch format {
+	case DocsMarkdown, "":
+		return markdownDocsTpl.Execute(w, d)
+	case DocsHTML:
+		return htmlDocsTpl.Execute(w, d)
+	}
+	return fmt.Errorf("Unknown docs format '%s', expected '%s' or '%s'", format, DocsMarkdown, DocsHTML)
+}
+
+// docsValue formats the value v as JSON, replacing it by plugins.RedactedValue,
+// if it is secret and not empty
+func docsValue(v interface{}, secret bool) string {
+	if v == nil {
+		return ""
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Slice && rv.IsNil() {
+		return "[]"
+	}
+	if secret {
+		empty := false
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Map:
+			empty = rv.Len() == 0
+		default:
+			empty = reflect.DeepEqual(v, reflect.Zero(rv.Type()).Interface())
+		}
+		if !empty {
+			return plugins.RedactedValue
+		}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -0,0 +1,84 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thlcodes/genfig/models"
+)
+
+func Test_WriteDocs(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	def := filepath.Join(tmpDir, "default.yml")
+	_ = ioutil.WriteFile(def, []byte("# name of the app\nname: app\ndb:\n  # the dsn @secret\n  dsn: postgres://localhost\n  pass: \"\"\n  port: 5432\n"), 0666)
+	prod := filepath.Join(tmpDir, "production.json")
+	_ = ioutil.WriteFile(prod, []byte(`{"db": {"dsn": "postgres://db", "pass": "secret"}}`), 0666)
+	files := []string{def, prod}
+
+	tests := []struct {
+		name     string
+		params   models.Params
+		format   string
+		contains []string
+		wantErr  string
+	}{
+		{"markdown", models.Params{}, DocsMarkdown, []string{
+			"Generated by genfig from 'default.yml', 'production.json'.",
+			"## Top level\n\n| Key | Type | Env vars | Default | production | Description |\n|-----|------|----------|---------|---|-------------|\n" +
+				"| `name` | `string` | `name`, `NAME` | `\"app\"` |  | name of the app |\n",
+			"## `db`\n",
+			"| `db.dsn` | `string` | `db.dsn`, `DB_DSN` | `[redacted]` | `[redacted]` | the dsn |\n",
+			"| `db.pass` | `string` | `db.pass`, `DB_PASS` | `\"\"` | `[redacted]` |  |\n",
+			"| `db.port` | `int64` | `db.port`, `DB_PORT` | `5432` |  |  |\n",
+		}, ""},
+		{"options", models.Params{
+			Plugins:       []string{"map"},
+			PluginOptions: map[string]map[string]string{"redact": {"patterns": ""}},
+		}, "", []string{
+			"| Key | Type | Default | production | Description |\n",
+			"| `db.pass` | `string` | `\"\"` | `\"secret\"` |  |\n",
+		}, ""},
+		{"html", models.Params{}, DocsHTML, []string{
+			"<h2><code>db</code></h2>",
+			"<tr><td><code>db.dsn</code></td><td><code>string</code></td><td><code>db.dsn</code>, <code>DB_DSN</code></td><td><code>[redacted]</code></td><td><code>[redacted]</code></td><td>the dsn</td></tr>",
+			"<tr><td><code>name</code></td><td><code>string</code></td><td><code>name</code>, <code>NAME</code></td><td><code>&#34;app&#34;</code></td><td></td><td>name of the app</td></tr>",
+		}, ""},
+		{"unknown format", models.Params{}, "pdf", nil, "Unknown docs format 'pdf', expected 'markdown' or 'html'"},
+		{"unknown plugin option", models.Params{PluginOptions: map[string]map[string]string{"redact": {"nope": ""}}}, "", nil, "Plugin 'redact' has no option 'nope'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &strings.Builder{}
+			err := WriteDocs(buf, files, tt.params, tt.format)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			for _, c := range tt.contains {
+				assert.Contains(t, buf.String(), c)
+			}
+		})
+	}
+
+	docs := filepath.Join(tmpDir, "CONFIG.html")
+	_, err := Generate(files, models.Params{Dir: filepath.Join(tmpDir, "config"), Docs: docs})
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(docs)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<h1>Configuration</h1>")
+}
+
+func Test_DocsFormat(t *testing.T) {
+	assert.Equal(t, DocsMarkdown, DocsFormat(""))
+	assert.Equal(t, DocsMarkdown, DocsFormat("CONFIG.md"))
+	assert.Equal(t, DocsHTML, DocsFormat("docs/config.HTML"))
+	assert.Equal(t, DocsHTML, DocsFormat("config.htm"))
+}
@@ -0,0 +1,254 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/plugins"
+	"github.com/thlcodes/genfig/writers"
+)
+
+// Formats of exported envs
+const (
+	ExportDotenv       = "dotenv"
+	ExportK8sConfigMap = "k8s-configmap"
+	ExportCompose      = "compose"
+	ExportJSON         = "json"
+	ExportYAML         = "yaml"
+)
+
+const (
+	defaultExportName = "app"
+)
+
+var (
+	// ExportFormats are all formats supported by Export
+	ExportFormats = []string{ExportDotenv, ExportK8sConfigMap, ExportCompose, ExportJSON, ExportYAML}
+	// dotenvSafeRe matches values, which can be written to .env files without quotes
+	dotenvSafeRe = regexp.MustCompile(`^[\w./:@,+=-]*$`)
+)
+
+// ExportOptions configure Export
+type ExportOptions struct {
+	// Env to export, the default env if empty
+	Env string
+	// Format is one of ExportFormats
+	Format string
+	// Name of the ConfigMap and of the compose service, 'app' if empty.
+	// The Secret holding the secret fields is named '<name>-secrets'
+	Name string
+}
+
+// k8sManifest is a ConfigMap or Secret
+type k8sManifest struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	StringData map[string]string `yaml:"stringData,omitempty"`
+}
+
+// Export writes the values of an env, merged over the ones of the default env like WriteConfig
+// does, in the given format to w. If the plugin 'substitutor' is selected, references to static
+// values are resolved. The formats 'dotenv', 'k8s-configmap' and 'compose' use the env vars set
+// by WriteToEnv. Secret fields (see plugins.IsSecret) are left out, except for 'k8s-configmap',
+// which puts them into a separate Secret. All keys are sorted, so the output is deterministic
+func Export(w io.Writer, files []string, params models.Params, opts ExportOptions) error {
+	if len(files) == 0 {
+		return errors.New("No files to export")
+	}
+	known := false
+	for _, f := range ExportFormats {
+		known = known || f == opts.Format
+	}
+	if !known {
+		return fmt.Errorf("Unknown export format '%s', expected one of '%s'", opts.Format, strings.Join(ExportFormats, "', '"))
+	}
+	if params.DefaultEnv == "" {
+		params.DefaultEnv = defaultEnvName
+	}
+	if opts.Env == "" {
+		opts.Env = params.DefaultEnv
+	}
+	if opts.Name == "" {
+		opts.Name = defaultExportName
+	}
+
+	l, schema, selectedPlugins, err := loadWithSchema(files, params)
+	if err != nil {
+		return err
+	}
+	data, found := l.data[opts.Env]
+	if !found {
+		return fmt.Errorf("Unknown environment '%s'", opts.Env)
+	}
+	references := false
+	for _, p := range selectedPlugins {
+		references = references || p.Name() == "substitutor"
+	}
+	if opts.Env != params.DefaultEnv {
+		if nonconformities := checkConformance(l.sources[opts.Env], data, l.positions[opts.Env], schema, references, params.Strict); len(nonconformities) > 0 {
+			return &ConformanceError{Nonconformities: nonconformities}
+		}
+	}
+
+	var merged map[string]interface{}
+	if references {
+		if merged, err = resolveReferences(l.data[params.DefaultEnv], data, schema); err == nil {
+			walkStrings("", merged, func(_ string, value string) string {
+				return unescapeReferences(value)
+			})
+		}
+	} else {
+		merged, err = writers.MergeConfig(l.data[params.DefaultEnv], data)
+	}
+	if err != nil {
+		return fileError(l.sources[opts.Env].File, err)
+	}
+
+	switch opts.Format {
+	case ExportJSON, ExportYAML:
+		return exportTree(w, merged, schema, opts.Format)
+	}
+	vars, secrets := exportVars(merged, schema)
+	switch opts.Format {
+	case ExportDotenv:
+		return exportDotenv(w, vars)
+	case ExportCompose:
+		compose := map[string]map[string]map[string]map[string]string{"services": {opts.Name: {"environment": {}}}}
+		for k, v := range vars {
+			// compose interpolates variables, unless escaped
+			compose["services"][opts.Name]["environment"][k] = strings.Replace(v, "$", "$$", -1)
+		}
+		return exportYAML(w, compose)
+	}
+	manifests := []k8sManifest{{APIVersion: "v1", Kind: "ConfigMap", Data: vars}}
+	manifests[0].Metadata.Name = opts.Name
+	if len(secrets) > 0 {
+		manifests = append(manifests, k8sManifest{APIVersion: "v1", Kind: "Secret", Type: "Opaque", StringData: secrets})
+		manifests[1].Metadata.Name = opts.Name + "-secrets"
+	}
+	for i, m := range manifests {
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return err
+			}
+		}
+		if err := exportYAML(w, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportVars returns the values of all fields of the config m by the names of the env vars
+// set by WriteToEnv, formatted like WriteToEnv does, separated into non-secret and secret ones
+func exportVars(m map[string]interface{}, schema models.SchemaMap) (map[string]string, map[string]string) {
+	flat := map[string]interface{}{}
+	flatten("", m, flat)
+	vars, secrets := map[string]string{}, map[string]string{}
+	for _, s := range schema {
+		if s.IsStruct {
+			continue
+		}
+		value := flat[s.DotPath()]
+		formatted := fmt.Sprintf("%v", value)
+		if strings.HasPrefix(s.Content, "[]") {
+			if rv := reflect.ValueOf(value); value == nil || rv.Kind() == reflect.Slice && rv.IsNil() {
+				formatted = "[]"
+			} else if data, err := json.Marshal(value); err == nil {
+				formatted = string(data)
+			}
+		}
+		if plugins.IsSecret(s) {
+			secrets[plugins.WriteToEnvName(s)] = formatted
+		} else {
+			vars[plugins.WriteToEnvName(s)] = formatted
+		}
+	}
+	return vars, secrets
+}
+
+// exportDotenv writes vars as sorted lines of a .env file, quoting values if needed
+func exportDotenv(w io.Writer, vars map[string]string) error {
+	keys := []string{}
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := vars[k]
+		switch {
+		case dotenvSafeRe.MatchString(v):
+		case !strings.ContainsAny(v, "'\n"):
+			v = "'" + v + "'"
+		default:
+			v = strconv.Quote(v)
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportTree writes the config m without its secret fields as json or yaml
+func exportTree(w io.Writer, m map[string]interface{}, schema models.SchemaMap, format string) error {
+	for _, s := range schema {
+		if !s.IsStruct && plugins.IsSecret(s) {
+			deletePath(m, strings.Split(s.DotPath(), "."))
+		}
+	}
+	if format == ExportYAML {
+		return exportYAML(w, m)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// exportYAML writes v as yaml, indented by two spaces
+func exportYAML(w io.Writer, v interface{}) error {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// deletePath deletes the value at the path of lowercase keys from m, whose keys may not be lowercase
+func deletePath(m map[string]interface{}, path []string) {
+	for k, v := range m {
+		if strings.ToLower(k) != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			delete(m, k)
+		} else if sub, isMap := v.(map[string]interface{}); isMap {
+			deletePath(sub, path[1:])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/thlcodes/genfig/models"
+)
+
+func Test_Export(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	def := filepath.Join(tmpDir, "default.yml")
+	_ = ioutil.WriteFile(def, []byte("name: app\nurl: http://${name}\ndb:\n  # the dsn @secret\n  dsn: postgres://localhost\n  pass: \"\"\n  port: 5432\nhosts: [\"x\"]\n"), 0666)
+	prod := filepath.Join(tmpDir, "production.json")
+	_ = ioutil.WriteFile(prod, []byte(`{"name": "my app", "db": {"dsn": "postgres://db", "pass": "it's $ecret"}, "hosts": ["a", "b"]}`), 0666)
+	nonconformant := filepath.Join(tmpDir, "staging.json")
+	_ = ioutil.WriteFile(nonconformant, []byte(`{"db": {"port": "nope"}}`), 0666)
+	files := []string{def, prod, nonconformant}
+
+	tests := []struct {
+		name    string
+		params  models.Params
+		opts    ExportOptions
+		want    string
+		wantErr string
+	}{
+		{"dotenv default", models.Params{}, ExportOptions{Format: ExportDotenv},
+			"DB_PORT=5432\nHOSTS='[\"x\"]'\nNAME=app\nURL=http://app\n", ""},
+		{"dotenv", models.Params{}, ExportOptions{Env: "production", Format: ExportDotenv},
+			"DB_PORT=5432\nHOSTS='[\"a\",\"b\"]'\nNAME='my app'\nURL='http://my app'\n", ""},
+		{"dotenv without substitutor", models.Params{DisabledPlugins: []string{"substitutor"}}, ExportOptions{Format: ExportDotenv},
+			"DB_PORT=5432\nHOSTS='[\"x\"]'\nNAME=app\nURL='http://${name}'\n", ""},
+		{"dotenv naming", models.Params{PluginOptions: map[string]map[string]string{"update_from_env": {"prefix": "APP_"}}}, ExportOptions{Format: ExportDotenv},
+			"APP_DB_PORT=5432\nAPP_HOSTS='[\"x\"]'\nAPP_NAME=app\nAPP_URL=http://app\n", ""},
+		{"compose", models.Params{}, ExportOptions{Env: "production", Format: ExportCompose, Name: "web"},
+			"services:\n  web:\n    environment:\n      DB_PORT: \"5432\"\n      HOSTS: '[\"a\",\"b\"]'\n      NAME: my app\n      URL: http://my app\n", ""},
+		{"k8s", models.Params{}, ExportOptions{Env: "production", Format: ExportK8sConfigMap},
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  DB_PORT: \"5432\"\n  HOSTS: '[\"a\",\"b\"]'\n  NAME: my app\n  URL: http://my app\n" +
+				"---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: app-secrets\ntype: Opaque\nstringData:\n  DB_DSN: postgres://db\n  DB_PASS: it's $ecret\n", ""},
+		{"k8s with marked secrets only", models.Params{PluginOptions: map[string]map[string]string{"redact": {"patterns": ""}}}, ExportOptions{Format: ExportK8sConfigMap},
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\ndata:\n  DB_PASS: \"\"\n  DB_PORT: \"5432\"\n  HOSTS: '[\"x\"]'\n  NAME: app\n  URL: http://app\n---\napiVersion: v1\nkind: Secret\nmetadata:\n  name: app-secrets\ntype: Opaque\nstringData:\n  DB_DSN: postgres://localhost\n", ""},
+		{"json", models.Params{}, ExportOptions{Env: "production", Format: ExportJSON},
+			"{\n  \"db\": {\n    \"port\": 5432\n  },\n  \"hosts\": [\n    \"a\",\n    \"b\"\n  ],\n  \"name\": \"my app\",\n  \"url\": \"http://my app\"\n}\n", ""},
+		{"yaml", models.Params{}, ExportOptions{Env: "production", Format: ExportYAML},
+			"db:\n  port: 5432\nhosts:\n  - a\n  - b\nname: my app\nurl: http://my app\n", ""},
+		{"unknown format", models.Params{}, ExportOptions{Format: "ini"}, "", "Unknown export format 'ini', expected one of 'dotenv', 'k8s-configmap', 'compose', 'json', 'yaml'"},
+		{"unknown env", models.Params{}, ExportOptions{Env: "nope", Format: ExportDotenv}, "", "Unknown environment 'nope'"},
+		{"nonconformant", models.Params{}, ExportOptions{Env: "staging", Format: ExportDotenv}, "", "staging.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &strings.Builder{}
+			err := Export(buf, files, tt.params, tt.opts)
+			if tt.wantErr != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), tt.wantErr)
+				}
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.want, buf.String())
+			}
+		})
+	}
+}
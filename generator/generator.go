@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/thlcodes/genfig/writers"
@@ -14,7 +15,7 @@ import (
 	"github.com/thlcodes/genfig/models"
 
 	"github.com/thlcodes/genfig/parsers"
-	"github.com/thlcodes/genfig/util"
+	"github.com/thlcodes/genfig/plugins"
 )
 
 const (
@@ -28,23 +29,12 @@ const (
 	defaultCmd              = "genfig"
 )
 
-var (
-	ymlStrategy    = parsers.YamlStrategy{}
-	tomlStrategy   = parsers.TomlStrategy{}
-	dotenvStrategy = parsers.DotenvStrategy{}
-)
-
 var (
 	allowedExtensions = []string{"\\.yml", "\\.yaml", "\\.json", "\\.toml"}
 	allowedPrefixes   = []string{"\\.env"}
-	parsersMap        = map[string]parsers.ParsingStrategy{
-		"yml":    &ymlStrategy,
-		"json":   &ymlStrategy,
-		"toml":   &tomlStrategy,
-		"dotenv": &dotenvStrategy,
-	}
-	envReStr = `((?:` + strings.Join(allowedPrefixes, "|") + `)\.([\w\.]+))|(([\w\.]+)(` + strings.Join(allowedExtensions, "|") + `))`
-	envRe    = regexp.MustCompile(envReStr)
+	parsersMap        = parsers.Strategies
+	envReStr          = `((?:` + strings.Join(allowedPrefixes, "|") + `)\.([\w\.]+))|(([\w\.]+)(` + strings.Join(allowedExtensions, "|") + `))`
+	envRe             = regexp.MustCompile(envReStr)
 )
 
 // Generate generates the go config files
@@ -57,46 +47,21 @@ func Generate(files []string, params models.Params) ([]string, error) {
 	if !filepath.IsAbs(params.Dir) {
 		params.Dir, _ = filepath.Abs(params.Dir)
 	}
-
-	envs := map[string]string{}
-	envMap := make(map[string]map[string]interface{})
-	fileMap := make(map[string]string)
-
-	for _, f := range files {
-		if _, err := os.Stat(f); err != nil {
-			return nil, err
-		}
-
-		env, typ := parseFilename(filepath.Base(f))
-		if env == "" {
-			continue
-		}
-		if _, exists := parsersMap[typ]; !exists {
-			continue
-		}
-		if _, exists := envMap[env]; exists {
-			return nil, fmt.Errorf("Environment '%s' does already exist", env)
-		}
-		var err error
-		envMap[env], err = parseFile(f, parsersMap[typ])
-		if err != nil {
-			return nil, err
-		}
-		fileMap[env] = f
-	}
-
-	if len(envMap) == 0 {
-		return nil, errors.New("No suitable config files found")
-	}
-
 	if params.DefaultEnv == "" {
 		params.DefaultEnv = defaultEnvName
 	}
 
-	var defaultEnv map[string]interface{}
-	var hasDefault bool
-	if defaultEnv, hasDefault = envMap[params.DefaultEnv]; !hasDefault {
-		return nil, errors.New("Missing default config")
+	l, err := loadEnvs(files, params.DefaultEnv)
+	if err != nil {
+		return nil, err
+	}
+	envs := map[string]string{}
+	envMap, srcMap, posMap, includeMap := l.data, l.sources, l.positions, l.includes
+	defaultEnv := envMap[params.DefaultEnv]
+
+	selectedPlugins, err := selectPlugins(params)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := os.MkdirAll(params.Dir, 0777); params.Dir != "" && err != nil {
@@ -105,10 +70,18 @@ func Generate(files []string, params models.Params) ([]string, error) {
 
 	gofiles := []string{}
 
+	// unexported fields of Config needed by the plugins
+	fields := []string{}
+	for _, p := range selectedPlugins {
+		if fp, ok := p.(plugins.FieldsPlugin); ok {
+			fields = append(fields, fp.Fields()...)
+		}
+	}
+
 	// write schemafile
 	var schema models.SchemaMap
 	schemaFileName := filepath.Join(params.Dir, defaultSchemaFilename)
-	source := fmt.Sprintf("%s (schema built from '%s')", defaultCmd, filepath.Base(fileMap[params.DefaultEnv]))
+	source := fmt.Sprintf("%s (schema built from '%s'%s)", defaultCmd, sourceName(srcMap[params.DefaultEnv]), includesDesc(includeMap[params.DefaultEnv]))
 	if err := func() (err error) {
 		var f *os.File
 		defer func() {
@@ -120,17 +93,71 @@ func Generate(files []string, params models.Params) ([]string, error) {
 			return err
 		} else if err = writers.WriteHeader(f, defaultPackage, source); err != nil {
 			return err
-		} else if schema, err = writers.WriteAndReturnSchema(f, defaultEnv); err != nil {
-			return err
+		} else if schema, err = writers.WriteAndReturnSchema(f, defaultEnv, fields...); err != nil {
+			return fileError(srcMap[params.DefaultEnv].File, err)
 		}
 		return
 	}(); err != nil {
 		return nil, err
 	}
 	gofiles = append(gofiles, schemaFileName)
+	schemaDocs(schema, posMap[params.DefaultEnv])
+	if err := checkMethodNames(srcMap[params.DefaultEnv], posMap[params.DefaultEnv], schema, selectedPlugins); err != nil {
+		return nil, fileError(srcMap[params.DefaultEnv].File, err)
+	}
+
+	// Check if all configs do conform to the schema of the default config.
+	// If one has additional fields or fields with a different type,
+	// it fails, reporting all nonconformities of all configs
+	envNames := []string{}
+	for env := range envMap {
+		envNames = append(envNames, env)
+	}
+	sort.Strings(envNames)
+	// non-string fields can only hold references, if they are substituted
+	references := false
+	for _, p := range selectedPlugins {
+		references = references || p.Name() == "substitutor"
+	}
+	nonconformities := []Nonconformity{}
+	for _, env := range envNames {
+		if env == params.DefaultEnv {
+			continue
+		}
+		nonconformities = append(nonconformities, checkConformance(srcMap[env], envMap[env], posMap[env], schema, references, params.Strict)...)
+	}
+	if len(nonconformities) > 0 {
+		return nil, &ConformanceError{Nonconformities: nonconformities}
+	}
+
+	// check, that all references can be substituted
+	if params.ResolveReferences && !references {
+		return nil, errors.New("Resolving references requires the plugin 'substitutor'")
+	}
+	for _, env := range envNames {
+		if !references {
+			break
+		}
+		warnings, err := checkReferences(srcMap[env], envMap[env], posMap[env], schema)
+		if err != nil {
+			return nil, fileError(srcMap[env].File, err)
+		}
+		for _, w := range warnings {
+			if params.Warnings != nil {
+				fmt.Fprintf(params.Warnings, "WARNING: %s\n", w)
+			}
+		}
+	}
 
-	// write config files
-	for env, data := range envMap {
+	// write config files, collecting the references of their non-string fields
+	envReferences := map[string]map[string]string{}
+	for _, env := range envNames {
+		data := envMap[env]
+		if params.ResolveReferences {
+			if data, err = resolveReferences(defaultEnv, data, schema); err != nil {
+				return nil, fileError(srcMap[env].File, err)
+			}
+		}
 		out := defaultConfigFilePrefix
 		if env == "test" {
 			out += "test_.go"
@@ -138,26 +165,10 @@ func Generate(files []string, params models.Params) ([]string, error) {
 			out += env + ".go"
 		}
 		path := filepath.Join(params.Dir, out)
-		source := fmt.Sprintf("%s (config built by merging '%s' and '%s')", defaultCmd, filepath.Base(fileMap[params.DefaultEnv]), filepath.Base(fileMap[env]))
-		name := strings.ReplaceAll(strings.Title(strings.ReplaceAll(env, "_", ".")), ".", "")
+		source := fmt.Sprintf("%s (config built by merging '%s' and '%s'%s)", defaultCmd, sourceName(srcMap[params.DefaultEnv]), sourceName(srcMap[env]), includesDesc(includeMap[params.DefaultEnv], includeMap[env]))
+		name := envName(env)
 		envs[env] = name
 
-		// Check of schema of this config does conform the the global schema
-		// If is has additional fields or fields with different schema themselves,
-		// it fails
-		var configSchema models.SchemaMap
-		if configSchema, err = writers.WriteAndReturnSchema(util.NoopWriter{}, data); err != nil {
-			return nil, err
-		}
-		for k, s := range configSchema {
-			if s.IsStruct {
-				continue
-			}
-			if _, exists := schema[k]; !exists || s.Content != schema[k].Content {
-				return nil, fmt.Errorf("%s has at leas one non-conformant field: '%s': %s != '%s'", fileMap[env], k, s.Content, schema[k].Content)
-			}
-		}
-
 		if err := func() (err error) {
 			var f *os.File
 			defer func() {
@@ -169,8 +180,8 @@ func Generate(files []string, params models.Params) ([]string, error) {
 				return err
 			} else if err = writers.WriteHeader(f, defaultPackage, source); err != nil {
 				return err
-			} else if err = writers.WriteConfig(f, schema, data, defaultEnv, name); err != nil {
-				return err
+			} else if envReferences[name], err = writers.WriteConfigAndReturnReferences(f, schema, data, defaultEnv, name); err != nil {
+				return fileError(srcMap[env].File, err)
 			}
 			return
 		}(); err != nil {
@@ -202,13 +213,30 @@ func Generate(files []string, params models.Params) ([]string, error) {
 	}
 	gofiles = append(gofiles, envsFileName)
 
-	pluginCalls := map[string]string{}
 	// write plugins files
+	for _, p := range selectedPlugins {
+		if sp, ok := p.(plugins.SourcesPlugin); ok {
+			sources := map[string]map[string]string{}
+			for _, env := range envNames {
+				sources[envName(env)] = valueSources(l, env, params.DefaultEnv, schema)
+			}
+			sp.SetSources(sources)
+		}
+		if rp, ok := p.(plugins.ReferencesPlugin); ok {
+			rp.SetReferences(envReferences)
+		}
+	}
 	var pfiles []string
-	if pfiles, err = writers.WritePlugins(schema, params.Dir, defaultPackage, defaultCmd, pluginCalls); err != nil {
+	if pfiles, err = writers.WritePlugins(selectedPlugins, schema, params.Dir, defaultPackage, defaultCmd); err != nil {
 		return nil, err
 	}
 	gofiles = append(gofiles, pfiles...)
+	initCalls := []plugins.InitCall{}
+	for _, p := range selectedPlugins {
+		if c, has := p.InitCall(); has {
+			initCalls = append(initCalls, c)
+		}
+	}
 
 	// write init file
 	initFileName := filepath.Join(params.Dir, defaultInitFilename)
@@ -223,37 +251,280 @@ func Generate(files []string, params models.Params) ([]string, error) {
 			return err
 		} else if err = writers.WriteHeader(f, defaultPackage, defaultCmd); err != nil {
 			return err
-		} else if err = writers.WriteInit(f, pluginCalls); err != nil {
+		} else if err = writers.WriteInit(f, initCalls, params.InitErrors); err != nil {
 			return err
 		}
 		return
 	}(); err != nil {
 		return nil, err
 	}
-	gofiles = append(gofiles, schemaFileName)
+	gofiles = append(gofiles, initFileName)
+
+	// write documentation
+	if params.Docs != "" {
+		if err := writeDocsFile(params.Docs, l, params.DefaultEnv, schema, selectedPlugins); err != nil {
+			return nil, err
+		}
+	}
 
 	return gofiles, nil
 }
 
-func parseFile(f string, s parsers.ParsingStrategy) (map[string]interface{}, error) {
+// loadedEnvs holds the parsed configs of all envs and where they were read from
+type loadedEnvs struct {
+	data      map[string]map[string]interface{}
+	sources   map[string]models.Position
+	positions map[string]models.PositionMap
+	includes  map[string][]string
+}
+
+// loadEnvs parses all config files and resolves their includes.
+// It fails, if an env is defined twice or the default env is missing
+func loadEnvs(files []string, defaultEnv string) (*loadedEnvs, error) {
+	l := &loadedEnvs{
+		data:      map[string]map[string]interface{}{},
+		sources:   map[string]models.Position{},
+		positions: map[string]models.PositionMap{},
+		includes:  map[string][]string{},
+	}
+	// parse all files and resolve their includes first, as files included
+	// by others are no envs, even if they match the given files
+	type loadedDoc struct {
+		file     string
+		doc      parsers.Document
+		includes []string
+	}
+	loaded := []loadedDoc{}
+	included := map[string]bool{}
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			return nil, fileError(f, err)
+		}
+
+		env, typ := parseFilename(filepath.Base(f))
+		if env == "" {
+			continue
+		}
+		if _, exists := parsersMap[typ]; !exists {
+			continue
+		}
+		docs, err := parseFile(f, parsersMap[typ])
+		if err != nil {
+			return nil, fileError(f, err)
+		}
+		for _, doc := range docs {
+			if doc.Env == "" {
+				doc.Env = env
+				doc.Index = 0
+			}
+			in := includer{}
+			if err := in.resolve(f, doc.Data, doc.Positions); err != nil {
+				return nil, fileError(f, err)
+			}
+			for _, inc := range in.files {
+				abs, _ := filepath.Abs(inc)
+				included[abs] = true
+			}
+			loaded = append(loaded, loadedDoc{f, doc, in.relativeFiles(f)})
+		}
+	}
+
+	for _, ld := range loaded {
+		if abs, _ := filepath.Abs(ld.file); included[abs] {
+			continue
+		}
+		f, doc := ld.file, ld.doc
+		if _, exists := l.data[doc.Env]; exists {
+			return nil, fileError(f, &parsers.ParseError{
+				Position: models.Position{Document: doc.Index},
+				Msg:      fmt.Sprintf("Environment '%s' does already exist", doc.Env),
+			})
+		}
+		l.data[doc.Env] = doc.Data
+		l.positions[doc.Env] = doc.Positions
+		l.sources[doc.Env] = models.Position{File: f, Document: doc.Index}
+		l.includes[doc.Env] = ld.includes
+	}
+
+	if len(l.data) == 0 {
+		return nil, errors.New("No suitable config files found")
+	}
+	if _, hasDefault := l.data[defaultEnv]; !hasDefault {
+		return nil, errors.New("Missing default config")
+	}
+	return l, nil
+}
+
+// parseFile parses all documents of the file f. If the parsing strategy does not
+// support multiple documents, the whole file is returned as single document
+func parseFile(f string, s parsers.ParsingStrategy) ([]parsers.Document, error) {
 	data, err := ioutil.ReadFile(f)
 	if err != nil {
 		return nil, err
 	}
-	return s.Parse(data)
+	if dp, ok := s.(parsers.DocumentsParser); ok {
+		return dp.ParseDocuments(data)
+	}
+	parsed, err := s.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	positions := models.PositionMap{}
+	if r, ok := s.(parsers.PositionReporter); ok {
+		if positions, err = r.Positions(data); err != nil {
+			return nil, err
+		}
+	}
+	return []parsers.Document{{Index: 1, Data: parsed, Positions: positions}}, nil
+}
+
+// schemaDocs sets the docs of all schema entries to the ones of their keys in the default config
+func schemaDocs(schema models.SchemaMap, positions models.PositionMap) {
+	docs := map[string]string{}
+	for k, p := range positions {
+		if p.Doc != "" {
+			docs[strings.ToLower(k)] = p.Doc
+		}
+	}
+	for n, s := range schema {
+		if doc, found := docs[s.DotPath()]; found {
+			s.Doc = doc
+			schema[n] = s
+		}
+	}
+}
+
+// checkMethodNames returns an error for the first key, whose field would be named like
+// a method generated by one of the selected plugins, e.g. 'clone'. Only top-level keys
+// clash with the methods of Config, all keys with the ones of all structs, e.g. 'db.string'
+func checkMethodNames(src models.Position, positions models.PositionMap, schema models.SchemaMap, selectedPlugins []plugins.Plugin) error {
+	fields := []models.Schema{}
+	for _, s := range schema {
+		if len(s.Segments()) > 0 {
+			fields = append(fields, s)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].DotPath() < fields[j].DotPath()
+	})
+	for _, p := range selectedPlugins {
+		// methods are the generated methods, true if generated for all structs
+		methods := map[string]bool{}
+		if mp, ok := p.(plugins.MethodsPlugin); ok {
+			for _, m := range mp.Methods() {
+				methods[m] = false
+			}
+		}
+		if sp, ok := p.(plugins.StructMethodsPlugin); ok {
+			for _, m := range sp.StructMethods() {
+				methods[m] = true
+			}
+		}
+		for _, s := range fields {
+			keys := s.Segments()
+			m := strings.Title(keys[len(keys)-1])
+			if allStructs, found := methods[m]; !found || len(keys) > 1 && !allStructs {
+				continue
+			}
+			pos, _ := lookupPosition(positions, strings.Join(keys, "."))
+			if pos.File == "" {
+				pos.File, pos.Document = src.File, src.Document
+			}
+			return &parsers.ParseError{Position: pos, Msg: fmt.Sprintf("Key '%s' is reserved, as its field would clash with the method '%s' generated by the plugin '%s'", strings.Join(keys, "."), m, p.Name())}
+		}
+	}
+	return nil
+}
+
+// envName returns the name of the field of an env in Envs, e.g. 'DevelopmentLocal'
+func envName(env string) string {
+	return strings.ReplaceAll(strings.Title(strings.ReplaceAll(env, "_", ".")), ".", "")
+}
+
+// sourceName returns the base name of the source file,
+// including the document index for multi-document files
+func sourceName(src models.Position) string {
+	if src.Document > 0 {
+		return fmt.Sprintf("%s#%d", filepath.Base(src.File), src.Document)
+	}
+	return filepath.Base(src.File)
+}
+
+// AvailablePlugins returns the built-in plugins plus the template plugins of params.PluginDir
+func AvailablePlugins(params models.Params) (plugins.Set, error) {
+	if params.PluginDir == "" {
+		return plugins.Plugins, nil
+	}
+	templates, err := plugins.LoadTemplatePlugins(params.PluginDir)
+	if err != nil {
+		return nil, err
+	}
+	return plugins.Plugins.With(templates)
+}
+
+// selectPlugins selects and configures the plugins according to params
+// and returns them in the order of their dependencies and init phases
+func selectPlugins(params models.Params) ([]plugins.Plugin, error) {
+	available, err := AvailablePlugins(params)
+	if err != nil {
+		return nil, err
+	}
+	selected, err := available.Select(params.Plugins, params.DisabledPlugins)
+	if err != nil {
+		return nil, err
+	}
+	for name := range params.PluginOptions {
+		if _, found := available.Find(name); !found {
+			return nil, fmt.Errorf("Options given for unknown plugin '%s'", name)
+		}
+	}
+	// configure all plugins, so that options of unselected ones are validated, too
+	for _, n := range available.Names() {
+		if err := available[n].Configure(params.PluginOptions[n]); err != nil {
+			return nil, err
+		}
+	}
+	return selected.Ordered()
+}
+
+// includesDesc describes all (unique) included files for the header of generated files
+func includesDesc(includes ...[]string) string {
+	files := []string{}
+	seen := map[string]bool{}
+	for _, inc := range includes {
+		for _, f := range inc {
+			if !seen[f] {
+				seen[f] = true
+				files = append(files, "'"+f+"'")
+			}
+		}
+	}
+	if len(files) == 0 {
+		return ""
+	}
+	return ", including " + strings.Join(files, ", ")
+}
+
+// fileError adds the path of the file f to err, if it does not contain it yet
+func fileError(f string, err error) error {
+	switch e := err.(type) {
+	case *parsers.ParseError:
+		if e.File == "" {
+			e.File = f
+		}
+		return e
+	case *os.PathError:
+		return e
+	default:
+		return fmt.Errorf("%s: %v", f, err)
+	}
 }
 
 func parseFilename(f string) (string, string) {
-	typ := filepath.Ext(f)
-	if len(typ) == 0 {
+	typ := parsers.FileType(f)
+	if typ == "" {
 		return "", ""
 	}
-	typ = typ[1:]
-	if typ == "yaml" {
-		typ = "yml"
-	} else if strings.HasPrefix(f, ".env") {
-		typ = "dotenv"
-	}
 
 	match := envRe.FindAllStringSubmatch(f, 1)
 	if len(match) == 0 {
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -8,8 +9,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/thlcodes/genfig/models"
 	"github.com/thlcodes/genfig/util"
+	"github.com/thlcodes/genfig/writers"
 )
 
 var (
@@ -37,7 +40,6 @@ func Test_Generate(t *testing.T) {
 	goodConfigFiles := append(configFilesWithoutDefault, configsDir+"/default.yml")
 	duplicateConfigFiles := []string{configsDir + "/local.yml", configsDir + "/local.yml"}
 	nonconformatnConfigFiles := []string{configsDir + "/default.yml", configsDir + "/nonconformant.yml"}
-	tooManyLevelsConfigFiles := []string{configsDir + "/default.with.too.many.levels.yml"}
 
 	goFiles := util.ReduceStrings(goodConfigFiles, func(r interface{}, s string) interface{} {
 		e, _ := parseFilename(s)
@@ -67,7 +69,6 @@ func Test_Generate(t *testing.T) {
 		{"not a config file", args{[]string{configsDir + "/notaconfig.txt"}, models.Params{}}, true},
 		{"duplicate env files", args{duplicateConfigFiles, models.Params{}}, true},
 		{"no default", args{configFilesWithoutDefault, models.Params{}}, true},
-		{"too many levels", args{tooManyLevelsConfigFiles, models.Params{DefaultEnv: "default.with.too.many.levels"}}, true},
 		{"additional field(s) to default", args{goodConfigFiles, models.Params{DefaultEnv: "local"}}, true},
 		{"non conformant value to default", args{nonconformatnConfigFiles, models.Params{}}, true},
 		{"existing files, no dir", args{goodConfigFiles, models.Params{}}, false},
@@ -101,6 +102,22 @@ func Test_Generate(t *testing.T) {
 	}
 }
 
+func Test_Generate_Levels(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	files := []string{filepath.Join(fixturesDir, "configs", "default.with.too.many.levels.yml")}
+	params := models.Params{Dir: tmpDir, DefaultEnv: "default.with.too.many.levels"}
+
+	_, err := Generate(files, params)
+	assert.NoError(t, err, "unlimited levels by default")
+
+	writers.SetMaxLevel(5)
+	defer writers.SetMaxLevel(0)
+	_, err = Generate(files, params)
+	assert.EqualError(t, err, files[0]+": Maximum of 5 levels exceeded")
+}
+
 func Test_parseFilename(t *testing.T) {
 	env := "environ.local"
 	tests := []struct {
@@ -126,3 +143,148 @@ func Test_parseFilename(t *testing.T) {
 		})
 	}
 }
+
+func Test_Generate_Errors(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	configsDir := filepath.Join(fixturesDir, "configs")
+	invalid := filepath.Join(tmpDir, ".env.invalid")
+	_ = ioutil.WriteFile(invalid, []byte("A=1\nB_C@=2\n"), 0666)
+	reserved := filepath.Join(tmpDir, "reserved", "default.yml")
+	_ = os.MkdirAll(filepath.Dir(reserved), 0777)
+	_ = ioutil.WriteFile(reserved, []byte("a: 1\nClone: true\n"), 0666)
+
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr string
+	}{
+		{"parse error", []string{configsDir + "/default.yml", invalid}, invalid + ":2:1: Key 'c@' is not valid"},
+		{"duplicate env", []string{configsDir + "/default.yml", configsDir + "/default.yml"}, configsDir + "/default.yml: Environment 'default' does already exist"},
+		{"reserved key", []string{reserved}, reserved + ":2:1: Key 'Clone' is reserved, as its field would clash with the method 'Clone' generated by the plugin 'deepcopy'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate(tt.files, models.Params{Dir: filepath.Join(tmpDir, "config")})
+			require.Error(t, err)
+			assert.Equal(t, tt.wantErr, err.Error())
+		})
+	}
+}
+
+func Test_Generate_Warnings(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	config := filepath.Join(tmpDir, "default.yml")
+	_ = ioutil.WriteFile(config, []byte("project: genfig\nname: ${projct}\nhome: ${HOME}\n"), 0666)
+	warnings := &bytes.Buffer{}
+	_, err := Generate([]string{config}, models.Params{Dir: filepath.Join(tmpDir, "config"), Plugins: []string{"deepcopy", "substitutor"}, Warnings: warnings})
+	require.NoError(t, err)
+	assert.Equal(t, "WARNING: "+config+":2:1: 'name' references 'projct', which is no key, so it is read from the env var 'projct'\n", warnings.String())
+}
+
+func Test_checkMethodNames(t *testing.T) {
+	schema := models.SchemaMap{
+		"Config":         models.Schema{IsStruct: true, Path: "Config"},
+		"ConfigSource":   models.Schema{Content: "string", Path: "Config_Source", Keys: []string{"source"}},
+		"ConfigDb":       models.Schema{IsStruct: true, Path: "Config_Db", Keys: []string{"db"}},
+		"ConfigDbDiff":   models.Schema{Content: "string", Path: "Config_Db_Diff", Keys: []string{"db", "diff"}},
+		"ConfigDbString": models.Schema{Content: "string", Path: "Config_Db_String", Keys: []string{"db", "string"}},
+	}
+	positions := models.PositionMap{"source": {Line: 2, Column: 1}}
+	tests := []struct {
+		name    string
+		plugins []string
+		wantErr string
+	}{
+		{"nested", []string{"deepcopy"}, ""},
+		{"provenance", []string{"provenance"}, "f:2:1: Key 'source' is reserved, as its field would clash with the method 'Source' generated by the plugin 'provenance'"},
+		{"redact", []string{"redact"}, "f: Key 'db.string' is reserved, as its field would clash with the method 'String' generated by the plugin 'redact'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := selectPlugins(models.Params{Plugins: tt.plugins})
+			require.NoError(t, err)
+			err = checkMethodNames(models.Position{File: "f"}, positions, schema, selected)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_Generate_MultiDocument(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	configsDir := filepath.Join(fixturesDir, "configs")
+	dir := filepath.Join(tmpDir, "config")
+
+	gofiles, err := Generate([]string{configsDir + "/multidoc.yml"}, models.Params{Dir: dir})
+	require.NoError(t, err)
+	assert.Contains(t, gofiles, filepath.Join(dir, defaultInitFilename))
+	assert.Equal(t, 1, strings.Count(strings.Join(gofiles, "\n"), filepath.Join(dir, defaultSchemaFilename)))
+	for _, f := range []string{"env_default.go", "env_staging.go", "env_production.go"} {
+		assert.FileExists(t, filepath.Join(dir, f))
+	}
+	staging, _ := ioutil.ReadFile(filepath.Join(dir, "env_staging.go"))
+	assert.Contains(t, string(staging), "merging 'multidoc.yml#1' and 'multidoc.yml#2'")
+	assert.Contains(t, string(staging), `Host: "staging.mydomain.com"`)
+
+	nonconformant := filepath.Join(tmpDir, "nonconformant.yml")
+	_ = ioutil.WriteFile(nonconformant, []byte("env: default\na: 1\n---\nenv: other\na: b\n"), 0666)
+	_, err = Generate([]string{nonconformant}, models.Params{Dir: dir})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "document 2, 5:1 'a': expected int64, got string")
+
+	duplicate := filepath.Join(tmpDir, "duplicate.yml")
+	_ = ioutil.WriteFile(duplicate, []byte("env: default\na: 1\n---\nenv: default\na: 2\n"), 0666)
+	_, err = Generate([]string{duplicate}, models.Params{Dir: dir})
+	require.Error(t, err)
+	assert.Equal(t, duplicate+": document 2: Environment 'default' does already exist", err.Error())
+}
+
+func Test_Generate_Plugins(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	files := []string{filepath.Join(fixturesDir, "configs", "default.yml")}
+	tests := []struct {
+		name        string
+		params      models.Params
+		wantPlugins []string
+		wantErr     bool
+	}{
+		{"all", models.Params{}, []string{"config_test", "deepcopy", "holder", "map", "redact", "redact_slog", "substitutor", "update_from_env", "write_to_env"}, false},
+		{"enabled", models.Params{Plugins: []string{"map"}}, []string{"map"}, false},
+		{"disabled", models.Params{DisabledPlugins: []string{"config_test"}}, []string{"deepcopy", "holder", "map", "redact", "redact_slog", "substitutor", "update_from_env", "write_to_env"}, false},
+		{"unknown plugin", models.Params{Plugins: []string{"nope"}}, nil, true},
+		{"options", models.Params{PluginOptions: map[string]map[string]string{"update_from_env": {"prefix": "APP_"}}}, []string{"config_test", "deepcopy", "holder", "map", "redact", "redact_slog", "substitutor", "update_from_env", "write_to_env"}, false},
+		{"options for unknown plugin", models.Params{PluginOptions: map[string]map[string]string{"nope": {"a": "b"}}}, nil, true},
+		{"unknown option", models.Params{PluginOptions: map[string]map[string]string{"map": {"a": "b"}}}, nil, true},
+		{"template plugins", models.Params{PluginDir: filepath.Join(fixturesDir, "plugins"), Plugins: []string{"map", "paths"}}, []string{"map", "paths"}, false},
+		{"template plugin options", models.Params{PluginDir: filepath.Join(fixturesDir, "plugins"), PluginOptions: map[string]map[string]string{"paths": {"prefix": "app"}}}, []string{"config_test", "deepcopy", "holder", "map", "paths", "redact", "redact_slog", "substitutor", "update_from_env", "write_to_env"}, false},
+		{"invalid init errors mode", models.Params{InitErrors: "nope"}, nil, true},
+		{"missing plugin dir", models.Params{PluginDir: filepath.Join(fixturesDir, "nope"), Plugins: []string{"paths"}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.Dir = filepath.Join(tmpDir, "config")
+			_, err := Generate(files, tt.params)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			found, _ := filepath.Glob(filepath.Join(tt.params.Dir, "plugin_*.go"))
+			got := util.MapString(found, func(s string) string {
+				return strings.TrimSuffix(strings.TrimPrefix(filepath.Base(s), "plugin_"), ".go")
+			})
+			assert.Equal(t, tt.wantPlugins, got)
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package generator
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/imdario/mergo"
+
+	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/parsers"
+)
+
+// includer resolves the include directives of a config file.
+// Included files are merged in the order of their declaration, so that later
+// ones override earlier ones; keys of the including map take precedence
+// over all included ones.
+type includer struct {
+	// chain of files currently being resolved, used to detect cycles
+	chain []string
+	// all included files in the order of their first inclusion
+	files []string
+}
+
+// resolve resolves all include directives of data, which was parsed from file
+func (in *includer) resolve(file string, data map[string]interface{}, positions models.PositionMap) error {
+	abs, _ := filepath.Abs(file)
+	in.chain = append(in.chain, abs)
+	defer func() { in.chain = in.chain[:len(in.chain)-1] }()
+	return in.resolveMap(file, "", data, positions)
+}
+
+// relativeFiles returns all included files relative to the directory of file
+func (in *includer) relativeFiles(file string) []string {
+	dir := filepath.Dir(file)
+	files := make([]string, len(in.files))
+	for i, f := range in.files {
+		if rel, err := filepath.Rel(dir, f); err == nil {
+			files[i] = rel
+		} else {
+			files[i] = f
+		}
+	}
+	return files
+}
+
+func (in *includer) resolveMap(file string, path string, m map[string]interface{}, positions models.PositionMap) error {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if sub, ok := m[k].(map[string]interface{}); ok {
+			if err := in.resolveMap(file, parsers.JoinPath(path, k), sub, positions); err != nil {
+				return err
+			}
+		}
+	}
+
+	raw, has := m[parsers.IncludeKey]
+	if !has {
+		return nil
+	}
+	pos := positions[parsers.JoinPath(path, parsers.IncludeKey)]
+	pos.File = file
+	delete(m, parsers.IncludeKey)
+	delete(positions, parsers.JoinPath(path, parsers.IncludeKey))
+
+	includes, ok := includePaths(raw)
+	if !ok {
+		return &parsers.ParseError{Position: pos, Msg: fmt.Sprintf("Invalid %s directive, expected a file or a list of files, got %#v", parsers.IncludeKey, raw)}
+	}
+
+	merged := map[string]interface{}{}
+	mergedPositions := models.PositionMap{}
+	for _, inc := range includes {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(file), inc)
+		}
+		data, incPositions, err := in.load(inc)
+		if err != nil {
+			if pe, ok := err.(*parsers.ParseError); ok && pe.File != "" {
+				return pe
+			}
+			return &parsers.ParseError{Position: pos, Msg: err.Error()}
+		}
+		if err := mergo.Merge(&merged, data, mergo.WithOverride); err != nil {
+			return &parsers.ParseError{Position: pos, Msg: err.Error()}
+		}
+		for k, p := range incPositions {
+			mergedPositions[parsers.JoinPath(path, k)] = p
+		}
+	}
+	if err := mergo.Merge(&merged, m, mergo.WithOverride); err != nil {
+		return &parsers.ParseError{Position: pos, Msg: err.Error()}
+	}
+	for k, v := range merged {
+		m[k] = v
+	}
+	for k, p := range mergedPositions {
+		if _, exists := positions[k]; !exists {
+			positions[k] = p
+		}
+	}
+	return nil
+}
+
+// load parses the included file f and resolves its include directives
+func (in *includer) load(f string) (map[string]interface{}, models.PositionMap, error) {
+	abs, _ := filepath.Abs(f)
+	for i, c := range in.chain {
+		if c == abs {
+			return nil, nil, fmt.Errorf("Include cycle detected: %s", strings.Join(append(in.chain[i:], abs), " -> "))
+		}
+	}
+
+	_, typ := parseFilename(filepath.Base(f))
+	strategy, exists := parsersMap[typ]
+	if !exists {
+		return nil, nil, fmt.Errorf("Cannot include '%s', unsupported file type", f)
+	}
+	docs, err := parseFile(f, strategy)
+	if err != nil {
+		return nil, nil, fileError(f, err)
+	}
+	if len(docs) != 1 {
+		return nil, nil, fmt.Errorf("Cannot include '%s', included files must consist of a single document", f)
+	}
+	doc := docs[0]
+	for k, p := range doc.Positions {
+		p.File = f
+		doc.Positions[k] = p
+	}
+
+	in.chain = append(in.chain, abs)
+	defer func() { in.chain = in.chain[:len(in.chain)-1] }()
+	if err := in.resolveMap(f, "", doc.Data, doc.Positions); err != nil {
+		return nil, nil, err
+	}
+
+	for _, existing := range in.files {
+		if existing == f {
+			return doc.Data, doc.Positions, nil
+		}
+	}
+	in.files = append(in.files, f)
+	return doc.Data, doc.Positions, nil
+}
+
+// includePaths returns the file(s) of an include directive
+func includePaths(raw interface{}) ([]string, bool) {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}, v != ""
+	case []interface{}:
+		paths := []string{}
+		for _, p := range v {
+			s, ok := p.(string)
+			if !ok || s == "" {
+				return nil, false
+			}
+			paths = append(paths, s)
+		}
+		return paths, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thlcodes/genfig/models"
+)
+
+func Test_includer(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"default.yml":        "a: 1\ndb: !include sub/db.toml\nqueue:\n  $include: [sub/queue.yml, .env.queue]\n  name: own\n",
+		"sub/db.toml":        "uri = \"mongodb://localhost\"\nuser = \"admin\"\n",
+		"sub/queue.yml":      "name: included\nsize: 10\nworkers: 1\n",
+		".env.queue":         "SIZE=20\n",
+		"cycle.yml":          "$include: sub/cycle.yml\n",
+		"sub/cycle.yml":      "$include: ../cycle.yml\n",
+		"invalid.yml":        "$include: 1\n",
+		"missing.yml":        "a:\n  $include: nope.yml\n",
+		"multi.yml":          "$include: multidoc.yml\n",
+		"multidoc.yml":       "env: a\n---\nenv: b\n",
+		"unsupported.yml":    "$include: unsupported.txt\n",
+		"unsupported.txt":    "a",
+		"nested.yml":         "$include: sub/nested.yml\n",
+		"sub/nested.yml":     "b:\n  $include: db.toml\n",
+		"sub/nonconform.yml": "uri: 1\n",
+		"nonconform.yml":     "db: !include sub/nonconform.yml\n",
+	}
+	for f, c := range files {
+		_ = os.MkdirAll(filepath.Dir(filepath.Join(tmpDir, f)), 0777)
+		_ = ioutil.WriteFile(filepath.Join(tmpDir, f), []byte(c), 0666)
+	}
+
+	tests := []struct {
+		name      string
+		file      string
+		want      map[string]interface{}
+		wantFiles []string
+		wantErr   string
+	}{
+		{"merged", "default.yml", map[string]interface{}{
+			"a":     1,
+			"db":    map[string]interface{}{"uri": "mongodb://localhost", "user": "admin"},
+			"queue": map[string]interface{}{"name": "own", "size": int64(20), "workers": 1},
+		}, []string{"sub/db.toml", "sub/queue.yml", ".env.queue"}, ""},
+		{"nested", "nested.yml", map[string]interface{}{
+			"b": map[string]interface{}{"uri": "mongodb://localhost", "user": "admin"},
+		}, []string{"sub/db.toml", "sub/nested.yml"}, ""},
+		{"cycle", "cycle.yml", nil, nil, "Include cycle detected: " + filepath.Join(tmpDir, "cycle.yml") + " -> " + filepath.Join(tmpDir, "sub/cycle.yml") + " -> " + filepath.Join(tmpDir, "cycle.yml")},
+		{"invalid", "invalid.yml", nil, nil, "Invalid $include directive, expected a file or a list of files, got 1"},
+		{"missing", "missing.yml", nil, nil, "no such file or directory"},
+		{"multiple documents", "multi.yml", nil, nil, "included files must consist of a single document"},
+		{"unsupported", "unsupported.yml", nil, nil, "unsupported file type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := filepath.Join(tmpDir, tt.file)
+			docs, err := parseFile(f, parsersMap["yml"])
+			require.NoError(t, err)
+			in := includer{}
+			err = in.resolve(f, docs[0].Data, docs[0].Positions)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, docs[0].Data)
+			assert.Equal(t, tt.wantFiles, in.relativeFiles(f))
+		})
+	}
+
+	t.Run("positions", func(t *testing.T) {
+		f := filepath.Join(tmpDir, "default.yml")
+		docs, _ := parseFile(f, parsersMap["yml"])
+		in := includer{}
+		require.NoError(t, in.resolve(f, docs[0].Data, docs[0].Positions))
+		assert.Equal(t, models.PositionMap{
+			"a":             {Line: 1, Column: 1},
+			"db":            {Line: 2, Column: 1},
+			"db.uri":        {File: filepath.Join(tmpDir, "sub/db.toml"), Line: 1, Column: 1},
+			"db.user":       {File: filepath.Join(tmpDir, "sub/db.toml"), Line: 2, Column: 1},
+			"queue":         {Line: 3, Column: 1},
+			"queue.name":    {Line: 5, Column: 3},
+			"queue.size":    {File: filepath.Join(tmpDir, ".env.queue"), Line: 1, Column: 1},
+			"queue.workers": {File: filepath.Join(tmpDir, "sub/queue.yml"), Line: 3, Column: 1},
+		}, docs[0].Positions)
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		dir := filepath.Join(tmpDir, "config")
+		_, err := Generate([]string{filepath.Join(tmpDir, "default.yml")}, models.Params{Dir: dir})
+		require.NoError(t, err)
+		schema, _ := ioutil.ReadFile(filepath.Join(dir, defaultSchemaFilename))
+		assert.Contains(t, string(schema), "schema built from 'default.yml', including 'sub/db.toml', 'sub/queue.yml', '.env.queue'")
+
+		_, err = Generate([]string{filepath.Join(tmpDir, "default.yml"), filepath.Join(tmpDir, "nonconform.yml")}, models.Params{Dir: dir})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), filepath.Join(tmpDir, "sub/nonconform.yml")+"\n\t1:1 'db.uri': expected string, got int64")
+	})
+
+	t.Run("included files are no envs", func(t *testing.T) {
+		layout := filepath.Join(tmpDir, "layout")
+		for f, c := range map[string]string{
+			"default.yml":    "db: !include db.yml\n",
+			"db.yml":         "uri: mongodb://localhost\n",
+			"production.yml": "db:\n  uri: mongodb://remote\n",
+		} {
+			_ = os.MkdirAll(layout, 0777)
+			_ = ioutil.WriteFile(filepath.Join(layout, f), []byte(c), 0666)
+		}
+		files, _ := filepath.Glob(filepath.Join(layout, "*"))
+		dir := filepath.Join(tmpDir, "layout-config")
+		gofiles, err := Generate(files, models.Params{Dir: dir})
+		require.NoError(t, err)
+		assert.Contains(t, gofiles, filepath.Join(dir, defaultConfigFilePrefix+"production.go"))
+		assert.NotContains(t, gofiles, filepath.Join(dir, defaultConfigFilePrefix+"db.go"))
+	})
+}
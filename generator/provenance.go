@@ -0,0 +1,139 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/util"
+	"github.com/thlcodes/genfig/writers"
+)
+
+// Explanation describes the value of a field of an env and where it came from
+type Explanation struct {
+	// Path is the dotted path of the field, e.g. 'db.uri'
+	Path  string
+	Value interface{}
+	// Source is the config file and line the value was read from, e.g. 'production.json:3'
+	Source string
+}
+
+// Explain returns the values of all fields of env with their sources, ordered by their paths,
+// as the plugin 'provenance' embeds them. If paths are given, only the fields at or below
+// them are returned. Env vars, overlay files and substitutions are not taken into account,
+// as they are only applied at runtime
+func Explain(files []string, params models.Params, env string, paths ...string) ([]Explanation, error) {
+	if len(files) == 0 {
+		return nil, errors.New("No files to explain")
+	}
+	if params.DefaultEnv == "" {
+		params.DefaultEnv = defaultEnvName
+	}
+	if env == "" {
+		env = params.DefaultEnv
+	}
+	l, err := loadEnvs(files, params.DefaultEnv)
+	if err != nil {
+		return nil, err
+	}
+	if _, found := l.data[env]; !found {
+		return nil, fmt.Errorf("Unknown environment '%s'", env)
+	}
+	defaultEnv := l.data[params.DefaultEnv]
+	schema, err := writers.WriteAndReturnSchema(util.NoopWriter{}, defaultEnv)
+	if err != nil {
+		return nil, fileError(l.sources[params.DefaultEnv].File, err)
+	}
+	merged, err := writers.MergeConfig(defaultEnv, l.data[env])
+	if err != nil {
+		return nil, fileError(l.sources[env].File, err)
+	}
+	values := map[string]interface{}{}
+	flatten("", merged, values)
+	sources := valueSources(l, env, params.DefaultEnv, schema)
+
+	explanations := []Explanation{}
+	found := map[string]bool{}
+	for path, source := range sources {
+		if len(paths) > 0 {
+			matched := false
+			for _, p := range paths {
+				if p = strings.ToLower(p); path == p || strings.HasPrefix(path, p+".") {
+					matched, found[p] = true, true
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		explanations = append(explanations, Explanation{Path: path, Value: values[path], Source: source})
+	}
+	for _, p := range paths {
+		if !found[strings.ToLower(p)] {
+			return nil, fmt.Errorf("Unknown key '%s'", p)
+		}
+	}
+	sort.Slice(explanations, func(i, j int) bool {
+		return explanations[i].Path < explanations[j].Path
+	})
+	return explanations, nil
+}
+
+// valueSources returns the sources of the values of all fields of env by their dotted paths,
+// i.e. the file of env for the fields it defines and the one of the default env for all others
+func valueSources(l *loadedEnvs, env string, defaultEnv string, schema models.SchemaMap) map[string]string {
+	defined := map[string]interface{}{}
+	flatten("", l.data[env], defined)
+	sources := map[string]string{}
+	for _, s := range schema {
+		if s.IsStruct {
+			continue
+		}
+		path := s.DotPath()
+		from := defaultEnv
+		if _, found := defined[path]; found {
+			from = env
+		}
+		sources[path] = valueSource(l.sources[from], l.positions[from], strings.Join(s.Segments(), "."))
+	}
+	return sources
+}
+
+// valueSource describes where the value at path was read from, e.g. 'default.yml:12'
+func valueSource(src models.Position, positions models.PositionMap, path string) string {
+	pos, found := lookupPosition(positions, path)
+	if !found {
+		return sourceName(src)
+	}
+	file := sourceName(src)
+	if pos.File != "" {
+		// an included file
+		file = filepath.Base(pos.File)
+	}
+	if pos.Line > 0 {
+		return fmt.Sprintf("%s:%d", file, pos.Line)
+	}
+	return file
+}
+
+// lookupPosition returns the position of the dotted path, or the one of the first path
+// (in sorted order) matching it case-insensitively, if there is no exact match
+func lookupPosition(positions models.PositionMap, path string) (models.Position, bool) {
+	if pos, found := positions[path]; found {
+		return pos, true
+	}
+	keys := []string{}
+	for k := range positions {
+		if strings.EqualFold(k, path) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return models.Position{}, false
+	}
+	sort.Strings(keys)
+	return positions[keys[0]], true
+}
@@ -0,0 +1,62 @@
+package generator
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thlcodes/genfig/models"
+)
+
+func Test_Explain(t *testing.T) {
+	configsDir := filepath.Join(fixturesDir, "configs")
+	files := []string{configsDir + "/default.yml", configsDir + "/production.json"}
+	tests := []struct {
+		name    string
+		env     string
+		paths   []string
+		want    []Explanation
+		wantErr string
+	}{
+		{"default", "", []string{"db.uri"}, []Explanation{{"db.uri", "mongdb://localhos:27017/db", "default.yml:10"}}, ""},
+		{"env", "production", []string{"Server", "db.user"}, []Explanation{
+			{"db.user", "", "default.yml:8"},
+			{"server.host", "mydomain.com", "production.json:5"},
+			{"server.port", 1234, "production.json:4"},
+		}, ""},
+		{"unknown env", "nope", nil, nil, "Unknown environment 'nope'"},
+		{"unknown key", "production", []string{"db.nope"}, nil, "Unknown key 'db.nope'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Explain(files, models.Params{}, tt.env, tt.paths...)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	all, err := Explain(files, models.Params{}, "production")
+	require.NoError(t, err)
+	assert.Len(t, all, 16)
+}
+
+func Test_valueSource(t *testing.T) {
+	src := models.Position{File: "/configs/default.yml"}
+	positions := models.PositionMap{
+		"db.Uri":  {Line: 3},
+		"db.uri":  {Line: 4},
+		"db.URI":  {Line: 5},
+		"db.Pass": {Line: 6},
+		"db.PASS": {Line: 7},
+		"db.user": {File: "/configs/db.yml", Line: 1},
+	}
+	assert.Equal(t, "default.yml:5", valueSource(src, positions, "db.URI"))
+	assert.Equal(t, "default.yml:7", valueSource(src, positions, "db.pass"))
+	assert.Equal(t, "db.yml:1", valueSource(src, positions, "db.user"))
+	assert.Equal(t, "default.yml", valueSource(src, positions, "db.host"))
+}
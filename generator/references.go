@@ -0,0 +1,294 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/parsers"
+	"github.com/thlcodes/genfig/writers"
+)
+
+var (
+	// envNameRe matches the names of references to env vars, as the plugin 'substitutor' does
+	envNameRe = regexp.MustCompile(`^\w+$`)
+	// referencedTypes are the types of fields, which can be referenced
+	referencedTypes = map[string]bool{"string": true, "int64": true, "uint64": true, "float64": true, "bool": true}
+)
+
+// reference is a reference within a string value, e.g. '${db.user:-admin}',
+// which starts and ends at the given indexes of the value
+type reference struct {
+	start, end    int
+	name, op, arg string
+}
+
+// parseReferences returns all references of value, skipping escaped ones ('$${').
+// The bool return value is false, if a reference is not closed
+func parseReferences(value string) ([]reference, bool) {
+	refs := []reference{}
+	for offset := 0; ; {
+		i := strings.Index(value[offset:], "${")
+		if i < 0 {
+			return refs, true
+		}
+		i += offset
+		if i > 0 && value[i-1] == '$' {
+			offset = i + 2
+			continue
+		}
+		end := strings.Index(value[i:], "}")
+		if end < 0 {
+			return refs, false
+		}
+		end += i
+		r := reference{start: i, end: end + 1, name: value[i+2 : end]}
+		if j := strings.Index(r.name, ":"); j >= 0 && j+1 < len(r.name) && (r.name[j+1] == '-' || r.name[j+1] == '?') {
+			r.name, r.op, r.arg = r.name[:j], r.name[j:j+2], r.name[j+2:]
+		}
+		refs = append(refs, r)
+		offset = end + 1
+	}
+}
+
+// referencedPaths returns the paths of all fields of the schema, which can be referenced
+func referencedPaths(schema models.SchemaMap) map[string]string {
+	paths := map[string]string{}
+	for _, s := range schema {
+		if !s.IsStruct && referencedTypes[s.Content] {
+			paths[s.DotPath()] = s.Content
+		}
+	}
+	return paths
+}
+
+// checkReferences returns an error for the first reference of config to an unknown key,
+// i.e. a reference, which is neither the path of a string, number or bool field
+// nor the name of an env var. Like paths, a name without dots is matched against the
+// (lowercase) top-level keys first, so naming a key, which cannot be referenced, is an
+// error as well. Otherwise it is the name of an env var. As env vars are expected to be
+// uppercase, a warning is returned for all other names of env vars, e.g. '${projct}'
+func checkReferences(src models.Position, config map[string]interface{}, positions models.PositionMap, schema models.SchemaMap) (warnings []string, err error) {
+	known := referencedPaths(schema)
+	topLevel := map[string]bool{}
+	for _, s := range schema {
+		if keys := s.Segments(); len(keys) == 1 {
+			topLevel[strings.ToLower(keys[0])] = true
+		}
+	}
+	walkStrings("", config, func(path string, value string) string {
+		if err != nil {
+			return value
+		}
+		refs, _ := parseReferences(value)
+		for _, r := range refs {
+			if _, found := known[r.name]; found {
+				continue
+			}
+			pos, found := positions[path]
+			if !found || pos.File == "" {
+				pos.File, pos.Document = src.File, src.Document
+			}
+			msg := fmt.Sprintf("'%s' references unknown key '%s'", path, r.name)
+			if envNameRe.MatchString(r.name) {
+				if topLevel[r.name] {
+					msg = fmt.Sprintf("'%s' references key '%s', which is no string, number or bool field", path, r.name)
+				} else {
+					if r.name != strings.ToUpper(r.name) {
+						warnings = append(warnings, (&parsers.ParseError{Position: pos, Msg: fmt.Sprintf("'%s' references '%s', which is no key, so it is read from the env var '%s'", path, r.name, r.name)}).Error())
+					}
+					continue
+				}
+			}
+			err = &parsers.ParseError{Position: pos, Msg: msg}
+			break
+		}
+		return value
+	})
+	return warnings, err
+}
+
+// walkStrings calls fn for all string values of m (but not of slices) by their dotted paths,
+// in the order of their paths, and replaces them by the returned values
+func walkStrings(prefix string, m map[string]interface{}, fn func(path string, value string) string) {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		path := k
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+		switch v := m[k].(type) {
+		case string:
+			m[k] = fn(path, v)
+		case map[string]interface{}:
+			walkStrings(path, v, fn)
+		}
+	}
+}
+
+// resolveReferences returns config merged over the default config def, where all
+// references to static values are replaced by these values. References to env vars
+// and to fields referencing env vars are kept, to be substituted at runtime
+func resolveReferences(def map[string]interface{}, config map[string]interface{}, schema models.SchemaMap) (map[string]interface{}, error) {
+	merged, err := writers.MergeConfig(def, config)
+	if err != nil {
+		return nil, err
+	}
+	r := &resolver{
+		types:     referencedPaths(schema),
+		values:    map[string]interface{}{},
+		results:   map[string]resolved{},
+		resolving: map[string]bool{},
+	}
+	flatten("", merged, r.values)
+	walkStrings("", merged, func(path string, value string) string {
+		path = strings.ToLower(path)
+		if r.types[path] != "string" {
+			return value
+		}
+		r.resolve(path)
+		return r.results[path].written
+	})
+	return merged, nil
+}
+
+// flatten adds all values of m to flat by their lowercase dotted paths
+func flatten(prefix string, m map[string]interface{}, flat map[string]interface{}) {
+	for k, v := range m {
+		path := strings.ToLower(k)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		if sub, isMap := v.(map[string]interface{}); isMap {
+			flatten(path, sub, flat)
+		} else {
+			flat[path] = v
+		}
+	}
+}
+
+// resolved is the result of resolving a string field
+type resolved struct {
+	// written is the value to be written, with static references replaced
+	written string
+	// final is the value after the substitution, if static
+	final  string
+	static bool
+}
+
+// resolver resolves the references of a single (merged) config
+type resolver struct {
+	// types of all fields, which can be referenced, by their paths
+	types     map[string]string
+	values    map[string]interface{}
+	results   map[string]resolved
+	resolving map[string]bool
+}
+
+// resolve returns the value of the field at path after the substitution
+// and whether it is static, i.e. independent of env vars
+func (r *resolver) resolve(path string) (string, bool) {
+	if res, done := r.results[path]; done {
+		return res.final, res.static
+	}
+	if r.resolving[path] {
+		// cycles are reported by the substitution at runtime
+		return "", false
+	}
+	value := r.values[path]
+	s, isString := value.(string)
+	if r.types[path] != "string" {
+		if isString {
+			// a reference of a non-string field
+			return "", false
+		}
+		return formatValue(value)
+	}
+	if !isString {
+		return "", false
+	}
+	r.resolving[path] = true
+	res := r.expand(s)
+	delete(r.resolving, path)
+	r.results[path] = res
+	return res.final, res.static
+}
+
+// expand replaces all references of value to static values
+func (r *resolver) expand(value string) resolved {
+	refs, closed := parseReferences(value)
+	if !closed {
+		return resolved{written: value}
+	}
+	written, final := strings.Builder{}, strings.Builder{}
+	static := true
+	last := 0
+	for _, ref := range refs {
+		written.WriteString(value[last:ref.start])
+		final.WriteString(unescapeReferences(value[last:ref.start]))
+		last = ref.end
+		if v, ok := r.lookup(ref); ok {
+			written.WriteString(escapeReferences(v))
+			final.WriteString(v)
+		} else {
+			written.WriteString(value[ref.start:ref.end])
+			static = false
+		}
+	}
+	written.WriteString(value[last:])
+	final.WriteString(unescapeReferences(value[last:]))
+	return resolved{written: written.String(), final: final.String(), static: static}
+}
+
+// lookup returns the value of the reference, if it is static
+func (r *resolver) lookup(ref reference) (string, bool) {
+	if _, known := r.types[ref.name]; !known {
+		// env var
+		return "", false
+	}
+	v, static := r.resolve(ref.name)
+	switch {
+	case !static:
+		return "", false
+	case v != "" || ref.op == "":
+		return v, true
+	case ref.op == ":-":
+		return ref.arg, true
+	}
+	// a missing required value is reported at runtime
+	return "", false
+}
+
+// formatValue formats a number or bool like the substitution at runtime does
+func formatValue(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case uint64:
+		return strconv.FormatUint(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// escapeReferences escapes all references in s, so that they are kept by the substitution
+func escapeReferences(s string) string {
+	return strings.Replace(s, "${", "$${", -1)
+}
+
+// unescapeReferences replaces escaped references like the substitution does
+func unescapeReferences(s string) string {
+	return strings.Replace(s, "$${", "${", -1)
+}
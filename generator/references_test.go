@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thlcodes/genfig/models"
+)
+
+func Test_parseReferences(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		want       []reference
+		wantClosed bool
+	}{
+		{"none", "a", []reference{}, true},
+		{"single", "${a.b}", []reference{{start: 0, end: 6, name: "a.b"}}, true},
+		{"default", "x${A:-d}y", []reference{{start: 1, end: 8, name: "A", op: ":-", arg: "d"}}, true},
+		{"required", "${A:?not set}", []reference{{start: 0, end: 13, name: "A", op: ":?", arg: "not set"}}, true},
+		{"escaped", "$${a}${b}", []reference{{start: 5, end: 9, name: "b"}}, true},
+		{"not closed", "${a}${b", []reference{{start: 0, end: 4, name: "a"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, closed := parseReferences(tt.value)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantClosed, closed)
+		})
+	}
+}
+
+func Test_checkReferences(t *testing.T) {
+	schema := models.SchemaMap{
+		"Config":      models.Schema{IsStruct: true, Path: "Config"},
+		"ConfigA":     models.Schema{Content: "string", Path: "Config_A"},
+		"ConfigB":     models.Schema{IsStruct: true, Path: "Config_B"},
+		"ConfigBC":    models.Schema{Content: "int64", Path: "Config_B_C"},
+		"ConfigBList": models.Schema{Content: "[]string", Path: "Config_B_List"},
+		"ConfigList":  models.Schema{Content: "[]string", Path: "Config_List"},
+	}
+	positions := models.PositionMap{"a": {Line: 1, Column: 4}}
+	tests := []struct {
+		name         string
+		config       map[string]interface{}
+		wantWarnings []string
+		wantErr      string
+	}{
+		{"known", map[string]interface{}{"a": "${b.c}", "b": map[string]interface{}{"c": "${a}"}}, nil, ""},
+		{"env vars", map[string]interface{}{"a": "${HOME:-/} ${X}"}, nil, ""},
+		{"escaped", map[string]interface{}{"a": "$${b.d}"}, nil, ""},
+		{"unknown", map[string]interface{}{"a": "${b.d}"}, nil, "f:1:4: 'a' references unknown key 'b.d'"},
+		{"slice", map[string]interface{}{"b": map[string]interface{}{"c": "${b.list}"}}, nil, "f: 'b.c' references unknown key 'b.list'"},
+		{"top-level struct", map[string]interface{}{"a": "${b}"}, nil, "f:1:4: 'a' references key 'b', which is no string, number or bool field"},
+		{"top-level slice", map[string]interface{}{"a": "${list}"}, nil, "f:1:4: 'a' references key 'list', which is no string, number or bool field"},
+		{"uppercase key", map[string]interface{}{"a": "${A} ${B} ${LIST}"}, nil, ""},
+		{"lowercase env var", map[string]interface{}{"a": "${aa} ${HOME}", "b": map[string]interface{}{"c": "${Home}"}}, []string{
+			"f:1:4: 'a' references 'aa', which is no key, so it is read from the env var 'aa'",
+			"f: 'b.c' references 'Home', which is no key, so it is read from the env var 'Home'",
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := checkReferences(models.Position{File: "f"}, tt.config, positions, schema)
+			assert.Equal(t, tt.wantWarnings, warnings)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_resolveReferences(t *testing.T) {
+	schema := models.SchemaMap{
+		"Config":        models.Schema{IsStruct: true, Path: "Config"},
+		"ConfigProject": models.Schema{Content: "string", Path: "Config_Project"},
+		"ConfigA":       models.Schema{Content: "string", Path: "Config_A"},
+		"ConfigB":       models.Schema{Content: "string", Path: "Config_B"},
+		"ConfigC":       models.Schema{Content: "string", Path: "Config_C"},
+		"ConfigPort":    models.Schema{Content: "int64", Path: "Config_Port"},
+		"ConfigDebug":   models.Schema{Content: "bool", Path: "Config_Debug"},
+	}
+	def := map[string]interface{}{"project": "genfig", "a": "", "b": "", "c": "", "port": int64(8080), "debug": false}
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   map[string]interface{}
+	}{
+		{"static", map[string]interface{}{"a": "${project}:${port}", "b": "${a}/${debug}"},
+			map[string]interface{}{"a": "genfig:8080", "b": "genfig:8080/false"}},
+		{"env vars", map[string]interface{}{"a": "${HOME}/${project}", "b": "${a}-${project}"},
+			map[string]interface{}{"a": "${HOME}/genfig", "b": "${a}-genfig"}},
+		{"defaults", map[string]interface{}{"a": "${c:-x}", "b": "${c:?required}"},
+			map[string]interface{}{"a": "x", "b": "${c:?required}"}},
+		{"escaped", map[string]interface{}{"a": "$${project}", "b": "${a}", "c": "${b}${project}"},
+			map[string]interface{}{"a": "$${project}", "b": "$${project}", "c": "$${project}genfig"}},
+		{"reference of non-string field", map[string]interface{}{"port": "${PORT}", "a": "${port}"},
+			map[string]interface{}{"port": "${PORT}", "a": "${port}"}},
+		{"cycle", map[string]interface{}{"a": "${b}", "b": "${a}", "c": "${project}"},
+			map[string]interface{}{"a": "${b}", "b": "${a}", "c": "genfig"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveReferences(def, tt.config, schema)
+			require.NoError(t, err)
+			for k, v := range tt.want {
+				assert.Equal(t, v, got[k], k)
+			}
+		})
+	}
+}
+
+func Test_Generate_ResolveReferences(t *testing.T) {
+	tmpDir, _ := ioutil.TempDir("", "genfig")
+	defer os.RemoveAll(tmpDir)
+
+	configsDir := fixturesDir + "/configs"
+	files := []string{configsDir + "/default.yml", configsDir + "/development.local.toml"}
+	_, err := Generate(files, models.Params{Dir: tmpDir, ResolveReferences: true})
+	require.NoError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(tmpDir, "env_development.local.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"mongdb://localhost:27017/genfig"`)
+
+	_, err = Generate(files, models.Params{Dir: tmpDir, ResolveReferences: true, Plugins: []string{"map"}})
+	assert.EqualError(t, err, "Resolving references requires the plugin 'substitutor'")
+}
@@ -5,6 +5,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -43,14 +44,43 @@ func main() {
 }
 
 func run() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "explain":
+			explain(os.Args[2:])
+			return
+		case "docs":
+			docs(os.Args[2:])
+			return
+		case "export":
+			export(os.Args[2:])
+			return
+		case "convert":
+			convert(os.Args[2:])
+			return
+		}
+	}
+
 	var (
 		helpFlag    = flag.Bool("help", false, "print this usage help")
 		versionFlag = flag.Bool("version", false, "print version")
-		maxLevel    = flag.Int("maxlevel", 5, "Maximum recursion level")
+		maxLevel    = flag.Int("maxlevel", 0, "maximum recursion level as safety net, 0 means unlimited")
 		dir         = flag.String("dir", "./config", "directory to write generated files into")
+		enabled     = flag.String("plugins", "", "comma separated list of plugins to write, all if empty")
+		disabled    = flag.String("disable-plugins", "", "comma separated list of plugins not to write")
+		listPlugins = flag.Bool("list-plugins", false, "list all available plugins")
+		pluginDir   = flag.String("plugin-dir", "", "directory of user-defined template plugins (*.tmpl)")
+		initErrors  = flag.String("init-errors", "log", "how the generated init handles errors: 'ignore', 'log' to stderr or 'panic'")
+		resolve     = flag.Bool("resolve", false, "resolve references between config values when generating, so that env vars overriding referenced values are not reflected")
+		strict      = flag.Bool("strict", false, "fail, if fields of the default config are missing in other configs, instead of using their default values")
+		docsFile    = flag.String("docs", "", "documentation file to write, in html for '*.html' files, otherwise in markdown")
+		pluginOpts  = pluginOptions{}
 	)
+	flag.Var(pluginOpts, "plugin-opt", "plugin option as 'plugin.option=value', can be repeated")
 
-	flag.Parse()
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		panic(err)
+	}
 
 	if *versionFlag {
 		fmt.Printf("%s %s", project, version)
@@ -60,6 +90,30 @@ func run() {
 		flag.Usage()
 		return
 	}
+	params := models.Params{
+		Dir:               *dir,
+		Plugins:           splitList(*enabled),
+		DisabledPlugins:   splitList(*disabled),
+		PluginDir:         *pluginDir,
+		PluginOptions:     pluginOpts,
+		InitErrors:        *initErrors,
+		ResolveReferences: *resolve,
+		Strict:            *strict,
+		Docs:              *docsFile,
+		Warnings:          os.Stdout,
+	}
+
+	if *listPlugins {
+		available, err := generator.AvailablePlugins(params)
+		if err != nil {
+			panic(err)
+		}
+		for _, n := range available.Names() {
+			p, _ := available.Find(n)
+			fmt.Printf("%s\t%s\n", n, p.Description())
+		}
+		return
+	}
 
 	writers.SetMaxLevel(*maxLevel)
 
@@ -75,9 +129,6 @@ func run() {
 		panic("No input files found")
 	}
 
-	params := models.Params{
-		Dir: *dir,
-	}
 	fmt.Printf("Generating from files: %s\n", strings.Join(files, ", "))
 
 	gofiles, err := generator.Generate(files, params)
@@ -99,3 +150,176 @@ func run() {
 
 	fmt.Printf("\nSuccessfully generated %d files: %s\n", len(gofiles), strings.Join(gofiles, ", "))
 }
+
+// explain prints the values of the given keys (all if none) of an env with the
+// config files and lines they are read from, e.g. 'genfig explain --env production db.uri'
+func explain(args []string) {
+	fs := flag.NewFlagSet(project+" explain", flag.ContinueOnError)
+	var (
+		env        = fs.String("env", "default", "environment to explain")
+		defaultEnv = fs.String("default-env", "default", "environment, whose config is the default one")
+		files      = fs.String("files", "*", "comma separated list of config files or globs")
+	)
+	if err := fs.Parse(args); err != nil {
+		panic(err)
+	}
+
+	resolved := util.ResolveGlobs(splitList(*files)...)
+	if len(resolved) == 0 {
+		panic("No input files found")
+	}
+	explanations, err := generator.Explain(resolved, models.Params{DefaultEnv: *defaultEnv}, *env, fs.Args()...)
+	if err != nil {
+		panic(fmt.Sprintf("%v", err))
+	}
+	for _, e := range explanations {
+		fmt.Printf("%s = %v (%s)\n", e.Path, e.Value, e.Source)
+	}
+}
+
+// docs writes the documentation of the config, e.g. 'genfig docs --out CONFIG.md default.yml production.json'
+func docs(args []string) {
+	fs := flag.NewFlagSet(project+" docs", flag.ContinueOnError)
+	var (
+		format     = fs.String("format", "", "format of the documentation, 'markdown' or 'html', given by the extension of the output file by default")
+		out        = fs.String("out", "", "file to write the documentation to, stdout if empty")
+		defaultEnv = fs.String("default-env", "default", "environment, whose config is the default one")
+		enabled    = fs.String("plugins", "", "comma separated list of selected plugins, all if empty")
+		disabled   = fs.String("disable-plugins", "", "comma separated list of plugins not selected")
+		pluginOpts = pluginOptions{}
+	)
+	fs.Var(pluginOpts, "plugin-opt", "plugin option as 'plugin.option=value', e.g. for the naming of env vars or the secret fields")
+	if err := fs.Parse(args); err != nil {
+		panic(err)
+	}
+
+	params := models.Params{
+		DefaultEnv:      *defaultEnv,
+		Plugins:         splitList(*enabled),
+		DisabledPlugins: splitList(*disabled),
+		PluginOptions:   pluginOpts,
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		args = []string{"*"}
+	}
+	files := util.ResolveGlobs(args...)
+	if len(files) == 0 {
+		panic("No input files found")
+	}
+	if *format == "" {
+		*format = generator.DocsFormat(*out)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := generator.WriteDocs(w, files, params, *format); err != nil {
+		panic(fmt.Sprintf("%v", err))
+	}
+}
+
+// export writes the values of an env for deployments, e.g. 'genfig export --env production --format k8s-configmap'
+func export(args []string) {
+	fs := flag.NewFlagSet(project+" export", flag.ContinueOnError)
+	var (
+		env        = fs.String("env", "default", "environment to export")
+		format     = fs.String("format", generator.ExportDotenv, "format of the export, one of '"+strings.Join(generator.ExportFormats, "', '")+"'")
+		name       = fs.String("name", "app", "name of the k8s ConfigMap and of the compose service")
+		out        = fs.String("out", "", "file to write the export to, stdout if empty")
+		defaultEnv = fs.String("default-env", "default", "environment, whose config is the default one")
+		enabled    = fs.String("plugins", "", "comma separated list of selected plugins, all if empty")
+		disabled   = fs.String("disable-plugins", "", "comma separated list of plugins not selected")
+		pluginOpts = pluginOptions{}
+	)
+	fs.Var(pluginOpts, "plugin-opt", "plugin option as 'plugin.option=value', e.g. for the naming of env vars or the secret fields")
+	if err := fs.Parse(args); err != nil {
+		panic(err)
+	}
+
+	params := models.Params{
+		DefaultEnv:      *defaultEnv,
+		Plugins:         splitList(*enabled),
+		DisabledPlugins: splitList(*disabled),
+		PluginOptions:   pluginOpts,
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		args = []string{"*"}
+	}
+	files := util.ResolveGlobs(args...)
+	if len(files) == 0 {
+		panic("No input files found")
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := generator.Export(w, files, params, generator.ExportOptions{Env: *env, Format: *format, Name: *name}); err != nil {
+		panic(fmt.Sprintf("%v", err))
+	}
+}
+
+// convert converts a config file into the format of another one, e.g. 'genfig convert production.json production.yml'
+func convert(args []string) {
+	fs := flag.NewFlagSet(project+" convert", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		panic(err)
+	}
+	if fs.NArg() != 2 {
+		panic("Expected the input and the output file, e.g. '" + project + " convert in.json out.yml'")
+	}
+	if err := generator.Convert(fs.Arg(0), fs.Arg(1)); err != nil {
+		panic(fmt.Sprintf("%v", err))
+	}
+	fmt.Printf("Converted '%s' to '%s'\n", fs.Arg(0), fs.Arg(1))
+}
+
+// pluginOptions collects plugin options given as 'plugin.option=value'
+type pluginOptions map[string]map[string]string
+
+func (o pluginOptions) String() string {
+	opts := []string{}
+	for p, kv := range o {
+		for k, v := range kv {
+			opts = append(opts, p+"."+k+"="+v)
+		}
+	}
+	return strings.Join(opts, ", ")
+}
+
+func (o pluginOptions) Set(s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	pk := strings.SplitN(kv[0], ".", 2)
+	if len(kv) != 2 || len(pk) != 2 || pk[0] == "" || pk[1] == "" {
+		return fmt.Errorf("invalid plugin option '%s', expected 'plugin.option=value'", s)
+	}
+	if _, exists := o[pk[0]]; !exists {
+		o[pk[0]] = map[string]string{}
+	}
+	o[pk[0]][pk[1]] = kv[1]
+	return nil
+}
+
+// splitList splits a comma separated list, ignoring empty entries
+func splitList(s string) []string {
+	list := []string{}
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
@@ -40,6 +40,31 @@ func Test_run(t *testing.T) {
 		{"with dir, no config files", []string{"-dir", out, "*"}, true},
 		{"without dir, valid config files", []string{configsDir + "/default.yml", configsDir + "/development.yml"}, false},
 		{"with dir, valid config files", []string{"-dir", out, configsDir + "/default.yml", configsDir + "/development.yml"}, false},
+		{"list plugins", []string{"--list-plugins"}, false},
+		{"selected plugins", []string{"-dir", out, "--plugins", "map, update_from_env", "--disable-plugins", "map", configsDir + "/default.yml"}, false},
+		{"unknown plugin", []string{"-dir", out, "--plugins", "nope", configsDir + "/default.yml"}, true},
+		{"plugin options", []string{"-dir", out, "--plugin-opt", "update_from_env.prefix=APP_", configsDir + "/default.yml"}, false},
+		{"invalid plugin option", []string{"-dir", out, "--plugin-opt", "prefix=APP_", configsDir + "/default.yml"}, true},
+		{"plugin dir", []string{"-dir", out, "--plugin-dir", filepath.Join(fixturesDir, "plugins"), configsDir + "/default.yml"}, false},
+		{"init errors", []string{"-dir", out, "--init-errors", "panic", configsDir + "/default.yml"}, false},
+		{"invalid init errors", []string{"-dir", out, "--init-errors", "nope", configsDir + "/default.yml"}, true},
+		{"resolve references", []string{"-dir", out, "--resolve", configsDir + "/default.yml", configsDir + "/development.local.toml"}, false},
+		{"resolve references without substitutor", []string{"-dir", out, "--resolve", "--disable-plugins", "substitutor", configsDir + "/default.yml"}, true},
+		{"explain", []string{"explain", "--env", "development", "--files", configsDir + "/default.yml," + configsDir + "/development.yaml", "db.uri"}, false},
+		{"explain unknown key", []string{"explain", "--files", configsDir + "/default.yml", "nope"}, true},
+		{"explain without files", []string{"explain", "db.uri"}, true},
+		{"docs", []string{"docs", configsDir + "/default.yml", configsDir + "/production.json"}, false},
+		{"docs to file", []string{"docs", "--out", filepath.Join(out, "CONFIG.html"), "--plugin-opt", "redact.fields=db.uri", configsDir + "/default.yml"}, false},
+		{"docs with unknown format", []string{"docs", "--format", "pdf", configsDir + "/default.yml"}, true},
+		{"export", []string{"export", "--env", "production", configsDir + "/default.yml", configsDir + "/production.json"}, false},
+		{"export to file", []string{"export", "--format", "k8s-configmap", "--out", filepath.Join(out, "configmap.yaml"), configsDir + "/default.yml"}, false},
+		{"export unknown env", []string{"export", "--env", "nope", configsDir + "/default.yml"}, true},
+		{"export with unknown format", []string{"export", "--format", "ini", configsDir + "/default.yml"}, true},
+		{"convert", []string{"convert", configsDir + "/default.yml", filepath.Join(out, "default.toml")}, false},
+		{"convert without output", []string{"convert", configsDir + "/default.yml"}, true},
+		{"convert unrepresentable", []string{"convert", configsDir + "/production.json", filepath.Join(out, ".env.production")}, true},
+		{"generate docs", []string{"-dir", out, "-docs", filepath.Join(out, "CONFIG.md"), configsDir + "/default.yml"}, false},
+		{"list plugins with plugin dir", []string{"--plugin-dir", filepath.Join(fixturesDir, "plugins"), "--list-plugins"}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
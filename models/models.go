@@ -1,10 +1,43 @@
 package models
 
+import (
+	"io"
+	"strings"
+)
+
 // Schema defines the schema
 type Schema struct {
 	IsStruct bool
 	Content  string
 	Path     string
+	// Keys are the keys of the config leading to the field, e.g. ['db', 'db_name'] for
+	// 'Config_Db_Db_name'. Unlike the path, they keep underscores within keys apart
+	Keys []string
+	// Doc is the comment documenting the key in the default config, if any
+	Doc string
+}
+
+// Segments returns the keys of the field, which are derived from its path, if not set
+func (s Schema) Segments() []string {
+	if len(s.Keys) > 0 {
+		return s.Keys
+	}
+	return strings.Split(s.Path, "_")[1:]
+}
+
+// DotPath returns the lowercase dotted path of the field, e.g. 'db.db_name'
+func (s Schema) DotPath() string {
+	return strings.ToLower(strings.Join(s.Segments(), "."))
+}
+
+// FieldPath returns the path of the Go field within Config, e.g. 'Db.Db_name'
+func (s Schema) FieldPath() string {
+	keys := s.Segments()
+	fields := make([]string, len(keys))
+	for i, k := range keys {
+		fields[i] = strings.Title(k)
+	}
+	return strings.Join(fields, ".")
 }
 
 // SchemaMap aliases as string-map of bytes
@@ -16,4 +49,42 @@ type Params struct {
 	Dir        string
 	DefaultEnv string
 	MergeFiles bool
+	// Plugins to be written (by name), all if empty
+	Plugins []string
+	// DisabledPlugins are not written, even if listed in Plugins
+	DisabledPlugins []string
+	// PluginDir holds user-defined template plugins (*.tmpl)
+	PluginDir string
+	// PluginOptions are passed to the plugins, e.g.
+	// {"update_from_env": {"prefix": "APP_"}}
+	PluginOptions map[string]map[string]string
+	// InitErrors defines how the generated init handles errors reported by
+	// the plugins: 'ignore', 'log' (default) to stderr or 'panic'
+	InitErrors string
+	// ResolveReferences resolves references between config values, e.g. '${db.user}',
+	// when generating, instead of at runtime. References to env vars are kept
+	ResolveReferences bool
+	// Strict also reports fields of the default config, which are missing in
+	// other envs, as nonconformities. Otherwise their default values are used
+	Strict bool
+	// Docs is the path of the documentation file to write, in html for '*.html'
+	// files, otherwise in markdown. None is written, if empty
+	Docs string
+	// Warnings receives warnings about the configs, one per line, e.g. about
+	// references, which might be typos of keys. They are dropped, if nil
+	Warnings io.Writer
 }
+
+// Position describes the location of a key within a config file.
+// Document is the index (starting with 1) of the document within a
+// multi-document file, otherwise 0. Doc is the comment documenting the key, if any
+type Position struct {
+	File     string
+	Document int
+	Line     int
+	Column   int
+	Doc      string
+}
+
+// PositionMap maps dotted key paths (e.g. 'db.uri') to their position
+type PositionMap map[string]Position
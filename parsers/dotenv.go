@@ -4,11 +4,11 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
-	"fmt"
 	"regexp"
 	"strings"
 
 	mergo "github.com/imdario/mergo"
+	"github.com/thlcodes/genfig/models"
 	"github.com/thlcodes/genfig/util"
 )
 
@@ -25,25 +25,49 @@ var (
 // e.g. `["a", "b", "c"]` ([]string) or `["a", 1, true]` ([]interface {}).
 // Key can be nests by eithe one of the allowed env separators, e.g. `DB_NAME` or `SERVER-HOST`
 // Comments are only allowed in seperate lines.
+// Other files can be included by (multiple) `$include=./other.env` lines.
 type DotenvStrategy struct {
 }
 
 // Parse of DotenvStrategy parses yaml and json files into Parsing result
 func (s *DotenvStrategy) Parse(data []byte) (map[string]interface{}, error) {
+	r, _, err := s.parse(data)
+	return r, err
+}
+
+// Positions of DotenvStrategy returns the positions of all keys of .env files
+func (s *DotenvStrategy) Positions(data []byte) (models.PositionMap, error) {
+	_, p, err := s.parse(data)
+	return p, err
+}
+
+func (s *DotenvStrategy) parse(data []byte) (map[string]interface{}, models.PositionMap, error) {
 	if len(data) == 0 {
-		return nil, errors.New("Empty data")
+		return nil, nil, errors.New("Empty data")
 	}
 
 	r := map[string]interface{}{}
+	p := models.PositionMap{}
+	includes := []interface{}{}
+	// comments preceding the current line
+	comments := []string{}
 
 	scanner := bufio.NewScanner(bytes.NewBuffer(data))
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// ignore empty or comment lines
-		if line == "" || strings.HasPrefix(line, "#") {
+	for l := 1; scanner.Scan(); l++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		col := len(raw) - len(strings.TrimLeft(raw, " \t")) + 1
+		// ignore empty or comment lines, but keep comments as doc of the following key
+		if line == "" {
+			comments = comments[:0]
+			continue
+		} else if strings.HasPrefix(line, "#") {
+			comments = append(comments, line)
 			continue
 		}
+		doc := commentText(comments...)
+		comments = comments[:0]
 		// try to split line into key and value by allowed separators
 		var kv []string
 		for i, sep := range allowedKVSeparators {
@@ -51,7 +75,7 @@ func (s *DotenvStrategy) Parse(data []byte) (map[string]interface{}, error) {
 				break
 			}
 			if i == len(allowedKVSeparators)-1 {
-				return nil, fmt.Errorf("Invalid dotenv line: '%s'", line)
+				return nil, nil, newParseError(l, col, "Invalid dotenv line: '%s'", line)
 			}
 		}
 
@@ -59,6 +83,14 @@ func (s *DotenvStrategy) Parse(data []byte) (map[string]interface{}, error) {
 		k := strings.TrimSpace(kv[0])
 		v := strings.TrimSpace(kv[1])
 
+		if strings.ToLower(k) == IncludeKey {
+			if _, exists := p[IncludeKey]; !exists {
+				p[IncludeKey] = models.Position{Line: l, Column: col}
+			}
+			includes = append(includes, v)
+			continue
+		}
+
 		var keys []string
 		for _, sep := range allowedEnvSeparators {
 			if keys = strings.Split(strings.ToLower(k), sep); len(keys) > 1 {
@@ -69,20 +101,33 @@ func (s *DotenvStrategy) Parse(data []byte) (map[string]interface{}, error) {
 		var item interface{} = r
 		for i, key := range keys {
 			if !keyRegex.MatchString(key) {
-				return nil, fmt.Errorf("Key '%s' is not valid", key)
+				return nil, nil, newParseError(l, col, "Key '%s' is not valid", key)
 			}
 			if item, found := item.(map[string]interface{})[key]; found {
 				switch item.(type) {
 				case map[string]interface{}:
 					if len(keys) == i+1 {
-						return nil, fmt.Errorf("Key '%s' is already present with differnt type (old: map, new: basic)", keys)
+						return nil, nil, newParseError(l, col, "Key '%s' is already present with differnt type (old: map, new: basic)", keys)
 					}
 				default:
-					return nil, fmt.Errorf("Key '%s' is already present with different type (old: basic, new: map)", keys)
+					return nil, nil, newParseError(l, col, "Key '%s' is already present with different type (old: basic, new: map)", keys)
 				}
 			}
 		}
 
+		for i := range keys {
+			kp := strings.Join(keys[:i+1], ".")
+			if _, exists := p[kp]; !exists {
+				p[kp] = models.Position{Line: l, Column: col}
+			}
+		}
+		if doc != "" {
+			kp := strings.Join(keys, ".")
+			pos := p[kp]
+			pos.Doc = doc
+			p[kp] = pos
+		}
+
 		util.ReverseStrings(keys)
 		tmp := map[string]interface{}{}
 
@@ -98,8 +143,12 @@ func (s *DotenvStrategy) Parse(data []byte) (map[string]interface{}, error) {
 	}
 
 	if err := scanner.Err(); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	if len(includes) > 0 {
+		r[IncludeKey] = includes
 	}
 
-	return r, nil
+	return r, p, nil
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/thlcodes/genfig/models"
 	. "github.com/thlcodes/genfig/parsers"
 )
 
@@ -45,6 +46,7 @@ func Test_Dotenv(t *testing.T) {
 		{"double occurency basic on map", args{[]byte("A_A=2\nA=1")}, nil, true},
 		{"nested double occurency", args{[]byte("A_A_A=2\nA_A=1")}, nil, true},
 		{"complex dotenv", args{[]byte(complexDotenv)}, complexDotenvResult, false},
+		{"includes", args{[]byte("$include=a.env\nA=1\n$INCLUDE: ./b.yml")}, map[string]interface{}{"a": int64(1), "$include": []interface{}{"a.env", "./b.yml"}}, false},
 	}
 	s := DotenvStrategy{}
 	for _, tt := range tests {
@@ -59,3 +61,65 @@ func Test_Dotenv(t *testing.T) {
 		})
 	}
 }
+
+func Test_DotenvPositions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    models.PositionMap
+		wantErr bool
+	}{
+		{"empty data", nil, nil, true},
+		{"invalid key", []byte("fooba@=12"), nil, true},
+		{"complex dotenv", []byte(complexDotenv), models.PositionMap{
+			"a":   {Line: 3, Column: 1, Doc: "this is a comment"},
+			"c":   {Line: 4, Column: 1},
+			"c.d": {Line: 4, Column: 1},
+			"c.e": {Line: 5, Column: 1},
+			"f":   {Line: 6, Column: 1},
+			"g":   {Line: 7, Column: 1},
+		}, false},
+		{"docs", []byte("# the a\n# of A\nA=1\n# detached\n\nB_C=2\n#c\nB_D=3"), models.PositionMap{
+			"a":   {Line: 3, Column: 1, Doc: "the a of A"},
+			"b":   {Line: 6, Column: 1},
+			"b.c": {Line: 6, Column: 1},
+			"b.d": {Line: 8, Column: 1, Doc: "c"},
+		}, false},
+	}
+	s := DotenvStrategy{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Positions(tt.data)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_DotenvErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantLine   int
+		wantColumn int
+		wantMsg    string
+	}{
+		{"invalid key", "A=1\n  fo@=2", 2, 3, "line 2:3: Key 'fo@' is not valid"},
+		{"invalid line", "A=1\n\n# comment\nB\n", 4, 1, "line 4:1: Invalid dotenv line: 'B'"},
+	}
+	s := DotenvStrategy{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Parse([]byte(tt.data))
+			require.Error(t, err)
+			require.IsType(t, &ParseError{}, err)
+			assert.Equal(t, tt.wantLine, err.(*ParseError).Line)
+			assert.Equal(t, tt.wantColumn, err.(*ParseError).Column)
+			assert.Equal(t, tt.wantMsg, err.Error())
+		})
+	}
+}
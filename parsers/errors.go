@@ -0,0 +1,67 @@
+package parsers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+var (
+	// matches the line information of yaml ("yaml: line 3: ...") and
+	// toml ("Near line 3 (last key parsed 'a'): ...") errors
+	lineRegex = regexp.MustCompile(`(?i)^(?:yaml: (?:unmarshal errors:\s*)?)?(?:near )?line (\d+)(?: \(last key parsed '[^']*'\))?:\s*`)
+)
+
+// ParseError is returned by the parsing strategies and holds
+// the position of the error, as far as it is known
+type ParseError struct {
+	models.Position
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	pos := []string{}
+	if e.File != "" {
+		pos = append(pos, e.File)
+	}
+	if e.Line > 0 {
+		if e.File == "" {
+			pos = append(pos, "line "+strconv.Itoa(e.Line))
+		} else {
+			pos = append(pos, strconv.Itoa(e.Line))
+		}
+		if e.Column > 0 {
+			pos = append(pos, strconv.Itoa(e.Column))
+		}
+	}
+	msg := e.Msg
+	if e.Document > 0 {
+		msg = "document " + strconv.Itoa(e.Document) + ": " + msg
+	}
+	if len(pos) == 0 {
+		return msg
+	}
+	return strings.Join(pos, ":") + ": " + msg
+}
+
+// newParseError creates a ParseError at the given position
+func newParseError(line int, col int, format string, a ...interface{}) *ParseError {
+	return &ParseError{Position: models.Position{Line: line, Column: col}, Msg: fmt.Sprintf(format, a...)}
+}
+
+// wrapParseError converts an error of a third party parser into a ParseError,
+// extracting the line from its message if possible
+func wrapParseError(err error) *ParseError {
+	if pe, ok := err.(*ParseError); ok {
+		return pe
+	}
+	msg := err.Error()
+	if m := lineRegex.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return newParseError(line, 0, "%s", msg[len(m[0]):])
+	}
+	return &ParseError{Msg: msg}
+}
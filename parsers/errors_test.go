@@ -0,0 +1,30 @@
+package parsers_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thlcodes/genfig/models"
+	. "github.com/thlcodes/genfig/parsers"
+)
+
+func Test_ParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		pos  models.Position
+		want string
+	}{
+		{"no position", models.Position{}, "msg"},
+		{"file only", models.Position{File: "a.yml"}, "a.yml: msg"},
+		{"line only", models.Position{Line: 2}, "line 2: msg"},
+		{"line and column", models.Position{Line: 2, Column: 3}, "line 2:3: msg"},
+		{"file and line", models.Position{File: "a.yml", Line: 2}, "a.yml:2: msg"},
+		{"full", models.Position{File: "a.yml", Line: 2, Column: 3}, "a.yml:2:3: msg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, (&ParseError{Position: tt.pos, Msg: "msg"}).Error())
+		})
+	}
+}
@@ -1,6 +1,101 @@
 package parsers
 
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/thlcodes/genfig/models"
+)
+
 // ParsingStrategy interface
 type ParsingStrategy interface {
 	Parse(data []byte) (map[string]interface{}, error)
 }
+
+// PositionReporter is implemented by parsing strategies, which are able
+// to report the positions of all keys of the parsed data
+type PositionReporter interface {
+	Positions(data []byte) (models.PositionMap, error)
+}
+
+const (
+	// IncludeKey is the key of include directives, e.g. `$include: ./db.yml`.
+	// Its value is either a single file or a list of files, relative to the including file
+	IncludeKey = "$include"
+	// IncludeTag is the yaml tag, which can be used instead of IncludeKey, e.g. `db: !include ./db.yml`
+	IncludeTag = "!include"
+)
+
+// DocumentEnvKey is the key, by which the documents of a multi-document
+// file declare their environment
+const DocumentEnvKey = "env"
+
+// Document is a single document of a (multi-document) file
+type Document struct {
+	// Index of the document within its file, starting with 1
+	Index int
+	// Env is the environment declared by the document,
+	// empty if the file consists of a single document
+	Env       string
+	Data      map[string]interface{}
+	Positions models.PositionMap
+}
+
+// DocumentsParser is implemented by parsing strategies, which support
+// files with multiple documents
+type DocumentsParser interface {
+	ParseDocuments(data []byte) ([]Document, error)
+}
+
+// Strategies holds the parsing strategies of all supported file types, see FileType
+var Strategies = map[string]ParsingStrategy{
+	"yml":    &YamlStrategy{},
+	"json":   &YamlStrategy{},
+	"toml":   &TomlStrategy{},
+	"dotenv": &DotenvStrategy{},
+}
+
+// FileType returns the type of the config file f, as used by Strategies,
+// e.g. 'yml' for 'default.yaml' or 'dotenv' for '.env.local'
+func FileType(f string) string {
+	f = filepath.Base(f)
+	typ := filepath.Ext(f)
+	if len(typ) == 0 {
+		return ""
+	}
+	typ = typ[1:]
+	if typ == "yaml" {
+		typ = "yml"
+	} else if strings.HasPrefix(f, ".env") {
+		typ = "dotenv"
+	}
+	return typ
+}
+
+// StrategyFor returns the parsing strategy for the config file f
+func StrategyFor(f string) (ParsingStrategy, bool) {
+	s, found := Strategies[FileType(f)]
+	return s, found
+}
+
+// commentText returns the text of comment lines without
+// their comment markers, joined by spaces
+func commentText(lines ...string) string {
+	texts := []string{}
+	for _, l := range lines {
+		for _, line := range strings.Split(l, "\n") {
+			if t := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#")); t != "" {
+				texts = append(texts, t)
+			}
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// JoinPath joins a dotted key path and a key
+func JoinPath(p string, k string) string {
+	if p == "" {
+		return k
+	}
+	return p + "." + k
+}
@@ -0,0 +1,36 @@
+package parsers_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/thlcodes/genfig/parsers"
+)
+
+func Test_FileType(t *testing.T) {
+	tests := []struct {
+		file     string
+		want     string
+		strategy ParsingStrategy
+	}{
+		{"default.yml", "yml", &YamlStrategy{}},
+		{"config/default.yaml", "yml", &YamlStrategy{}},
+		{"production.json", "json", &YamlStrategy{}},
+		{"local.toml", "toml", &TomlStrategy{}},
+		{".env", "dotenv", &DotenvStrategy{}},
+		{"config/.env.local", "dotenv", &DotenvStrategy{}},
+		{"notaconfig.txt", "txt", nil},
+		{"noext", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			assert.Equal(t, tt.want, FileType(tt.file))
+			s, found := StrategyFor(tt.file)
+			assert.Equal(t, tt.strategy != nil, found)
+			if tt.strategy != nil {
+				assert.IsType(t, tt.strategy, s)
+			}
+		})
+	}
+}
@@ -1,9 +1,14 @@
 package parsers
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
+	"strconv"
+	"strings"
 
 	toml "github.com/BurntSushi/toml"
+	"github.com/thlcodes/genfig/models"
 )
 
 // TomlStrategy parses yaml and json files
@@ -19,8 +24,173 @@ func (s *TomlStrategy) Parse(data []byte) (map[string]interface{}, error) {
 
 	err := toml.Unmarshal(data, &r)
 	if err != nil {
-		return nil, err
+		return nil, wrapParseError(err)
 	}
 
 	return r, nil
 }
+
+// Positions of TomlStrategy returns the positions of all keys of toml files.
+// Since the toml decoder does not expose any positions, the data is scanned line by line.
+// Keys of arrays of tables and inline tables are not reported.
+func (s *TomlStrategy) Positions(data []byte) (models.PositionMap, error) {
+	if len(data) == 0 {
+		return nil, errors.New("Empty data")
+	}
+	p := models.PositionMap{}
+	// comments preceding the current line
+	comments := []string{}
+	add := func(path []string, line int, col int, inline string) {
+		for i := range path {
+			kp := strings.Join(path[:i+1], ".")
+			if _, exists := p[kp]; !exists {
+				p[kp] = models.Position{Line: line, Column: col}
+			}
+		}
+		if doc := commentText(append(comments, inline)...); doc != "" && len(path) > 0 {
+			kp := strings.Join(path, ".")
+			pos := p[kp]
+			pos.Doc = doc
+			p[kp] = pos
+		}
+	}
+
+	var (
+		table   []string
+		inArray bool   // within an array of tables
+		closing string // closing delimiter of a multi-line string
+		depth   int    // bracket depth of a multi-line array
+	)
+	scanner := bufio.NewScanner(bytes.NewBuffer(data))
+	for l := 1; scanner.Scan(); l++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		col := len(raw) - len(strings.TrimLeft(raw, " \t")) + 1
+		switch {
+		case closing != "":
+			if strings.Contains(line, closing) {
+				closing = ""
+			}
+			continue
+		case depth > 0:
+			depth += tomlBracketDepth(line)
+			continue
+		case line == "":
+			comments = comments[:0]
+			continue
+		case line[0] == '#':
+			comments = append(comments, line)
+			continue
+		case strings.HasPrefix(line, "[["):
+			if end := strings.Index(line, "]]"); end > 0 {
+				add(tomlKey(line[2:end]), l, col, tomlComment(line[end+2:]))
+			}
+			inArray = true
+			comments = comments[:0]
+			continue
+		case line[0] == '[':
+			if end := strings.Index(line, "]"); end > 0 {
+				table = tomlKey(line[1:end])
+				add(table, l, col, tomlComment(line[end+1:]))
+			}
+			inArray = false
+			comments = comments[:0]
+			continue
+		}
+		eq := tomlIndexOutsideQuotes(line, '=')
+		if eq < 0 {
+			comments = comments[:0]
+			continue
+		}
+		value := strings.TrimSpace(line[eq+1:])
+		if !inArray {
+			add(append(append([]string{}, table...), tomlKey(line[:eq])...), l, col, tomlComment(value))
+		}
+		comments = comments[:0]
+		for _, q := range []string{`"""`, `'''`} {
+			if strings.HasPrefix(value, q) && !strings.Contains(value[len(q):], q) {
+				closing = q
+			}
+		}
+		if strings.HasPrefix(value, "[") {
+			depth = tomlBracketDepth(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// tomlComment returns the comment at the end of a line, if any
+func tomlComment(s string) string {
+	if i := tomlIndexOutsideQuotes(s, '#'); i >= 0 {
+		return s[i:]
+	}
+	return ""
+}
+
+// tomlKey splits a (dotted) toml key into its unquoted parts
+func tomlKey(s string) []string {
+	parts := []string{}
+	for {
+		dot := tomlIndexOutsideQuotes(s, '.')
+		part := s
+		if dot >= 0 {
+			part = s[:dot]
+		}
+		part = strings.TrimSpace(part)
+		if unquoted, err := strconv.Unquote(part); err == nil {
+			part = unquoted
+		} else if len(part) > 1 && part[0] == '\'' && part[len(part)-1] == '\'' {
+			part = part[1 : len(part)-1]
+		}
+		parts = append(parts, part)
+		if dot < 0 {
+			return parts
+		}
+		s = s[dot+1:]
+	}
+}
+
+// tomlIndexOutsideQuotes returns the index of the first c, which is
+// not part of a quoted string, or -1
+func tomlIndexOutsideQuotes(s string, c byte) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' && quote == '"' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == c:
+			return i
+		case s[i] == '#':
+			return -1
+		}
+	}
+	return -1
+}
+
+// tomlBracketDepth returns the difference of opening and closing brackets,
+// which are not part of a quoted string or comment
+func tomlBracketDepth(s string) (depth int) {
+	for {
+		i := tomlIndexOutsideQuotes(s, '[')
+		j := tomlIndexOutsideQuotes(s, ']')
+		switch {
+		case i < 0 && j < 0:
+			return
+		case j < 0 || (i >= 0 && i < j):
+			depth++
+			s = s[i+1:]
+		default:
+			depth--
+			s = s[j+1:]
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/thlcodes/genfig/models"
 	. "github.com/thlcodes/genfig/parsers"
 )
 
@@ -56,3 +57,72 @@ func Test_Toml(t *testing.T) {
 		})
 	}
 }
+
+func Test_TomlPositions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    models.PositionMap
+		wantErr bool
+	}{
+		{"empty data", nil, nil, true},
+		{"complex toml", []byte(complexToml), models.PositionMap{
+			"a":   {Line: 2, Column: 2},
+			"f":   {Line: 3, Column: 2},
+			"c":   {Line: 9, Column: 2},
+			"c.d": {Line: 10, Column: 2},
+			"c.e": {Line: 11, Column: 2},
+		}, false},
+		{"dotted and quoted keys", []byte("a.\"b.c\" = 1\n[d]\n'e' = \"\"\"\nf = 2\n\"\"\"\ng = 3 # h = 4"), models.PositionMap{
+			"a":     {Line: 1, Column: 1},
+			"a.b.c": {Line: 1, Column: 1},
+			"d":     {Line: 2, Column: 1},
+			"d.e":   {Line: 3, Column: 1},
+			"d.g":   {Line: 6, Column: 1, Doc: "h = 4"},
+		}, false},
+		{"docs", []byte("# the a\na = 1 # more\n# detached\n\n# the table\n[b] # b\n# c of b\nc = 'x # y'\n"), models.PositionMap{
+			"a":   {Line: 2, Column: 1, Doc: "the a more"},
+			"b":   {Line: 6, Column: 1, Doc: "the table b"},
+			"b.c": {Line: 8, Column: 1, Doc: "c of b"},
+		}, false},
+		{"array of tables", []byte("[[a]]\nb = 1\n[c]\nd = 2"), models.PositionMap{
+			"a":   {Line: 1, Column: 1},
+			"c":   {Line: 3, Column: 1},
+			"c.d": {Line: 4, Column: 1},
+		}, false},
+	}
+	s := TomlStrategy{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Positions(tt.data)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_TomlErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantLine int
+		wantMsg  string
+	}{
+		{"missing value", "a = 1\nb = \n", 2, "line 2: expected value but found '\\n' instead"},
+		{"unclosed table", "a = 1\n[b\n", 2, "line 2: expected '.' or ']' to end table name, but got '\\n' instead"},
+	}
+	s := TomlStrategy{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Parse([]byte(tt.data))
+			require.Error(t, err)
+			require.IsType(t, &ParseError{}, err)
+			assert.Equal(t, tt.wantLine, err.(*ParseError).Line)
+			assert.Equal(t, tt.wantMsg, err.Error())
+		})
+	}
+}
@@ -1,8 +1,11 @@
 package parsers
 
 import (
+	"bytes"
 	"errors"
+	"io"
 
+	"github.com/thlcodes/genfig/models"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -17,10 +20,138 @@ func (s *YamlStrategy) Parse(data []byte) (map[string]interface{}, error) {
 	}
 	r := map[string]interface{}{}
 
-	err := yaml.Unmarshal(data, r)
-	if err != nil {
-		return nil, err
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, wrapParseError(err)
+	}
+	yamlIncludeTags(&doc)
+	if err := doc.Decode(r); err != nil {
+		return nil, wrapParseError(err)
 	}
 
 	return r, nil
 }
+
+// Positions of YamlStrategy returns the positions of all keys of yaml and json files
+func (s *YamlStrategy) Positions(data []byte) (models.PositionMap, error) {
+	if len(data) == 0 {
+		return nil, errors.New("Empty data")
+	}
+	doc := yaml.Node{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, wrapParseError(err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, errors.New("Data is not a map")
+	}
+	p := models.PositionMap{}
+	yamlIncludeTags(&doc)
+	yamlPositions(doc.Content[0], "", p)
+	return p, nil
+}
+
+// ParseDocuments of YamlStrategy parses all documents of a yaml file.
+// If there is more than one document, each one has to declare its environment
+// by the top level key 'env', which is removed from the document's data.
+func (s *YamlStrategy) ParseDocuments(data []byte) ([]Document, error) {
+	if len(data) == 0 {
+		return nil, errors.New("Empty data")
+	}
+	nodes := []*yaml.Node{}
+	dec := yaml.NewDecoder(bytes.NewBuffer(data))
+	for {
+		n := &yaml.Node{}
+		if err := dec.Decode(n); err == io.EOF {
+			break
+		} else if err != nil {
+			pe := wrapParseError(err)
+			if len(nodes) > 0 {
+				pe.Document = len(nodes) + 1
+			}
+			return nil, pe
+		}
+		nodes = append(nodes, n)
+	}
+
+	docs := []Document{}
+	for i, n := range nodes {
+		doc := Document{Index: i + 1, Data: map[string]interface{}{}, Positions: models.PositionMap{}}
+		yamlIncludeTags(n)
+		if err := n.Decode(doc.Data); err != nil {
+			pe := wrapParseError(err)
+			if len(nodes) > 1 {
+				pe.Document = doc.Index
+			}
+			return nil, pe
+		}
+		if len(n.Content) > 0 {
+			yamlPositions(n.Content[0], "", doc.Positions)
+		}
+		if len(nodes) > 1 {
+			for k, p := range doc.Positions {
+				p.Document = doc.Index
+				doc.Positions[k] = p
+			}
+			env, ok := doc.Data[DocumentEnvKey].(string)
+			if !ok || env == "" {
+				return nil, &ParseError{Position: models.Position{Document: doc.Index, Line: n.Line, Column: n.Column}, Msg: "Document does not declare its '" + DocumentEnvKey + "'"}
+			}
+			doc.Env = env
+			delete(doc.Data, DocumentEnvKey)
+			delete(doc.Positions, DocumentEnvKey)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, errors.New("No documents found")
+	}
+	return docs, nil
+}
+
+// yamlPositions walks a yaml mapping node and collects the positions of its keys
+func yamlPositions(n *yaml.Node, path string, p models.PositionMap) {
+	if n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Value == "<<" {
+			continue
+		}
+		kp := JoinPath(path, k.Value)
+		doc := commentText(k.HeadComment)
+		if doc == "" {
+			doc = commentText(k.LineComment, v.LineComment)
+		}
+		p[kp] = models.Position{Line: k.Line, Column: k.Column, Doc: doc}
+		yamlPositions(v, kp, p)
+	}
+}
+
+// yamlIncludeTags replaces all values tagged with '!include' by a map
+// containing the according include directive, e.g. `db: !include db.yml`
+// becomes `db: {$include: db.yml}`
+func yamlIncludeTags(n *yaml.Node) {
+	for _, c := range n.Content {
+		yamlIncludeTags(c)
+	}
+	if n.Tag != IncludeTag {
+		return
+	}
+	value := *n
+	value.Tag = ""
+	value.Style &^= yaml.TaggedStyle
+	*n = yaml.Node{
+		Kind:   yaml.MappingNode,
+		Tag:    "!!map",
+		Line:   n.Line,
+		Column: n.Column,
+		Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Tag: "!!str", Value: IncludeKey, Line: n.Line, Column: n.Column},
+			&value,
+		},
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/thlcodes/genfig/models"
 	. "github.com/thlcodes/genfig/parsers"
 )
 
@@ -56,6 +57,10 @@ func Test_Yaml(t *testing.T) {
 		{"vaild json", args{[]byte(`{"a": 1}`)}, map[string]interface{}{"a": 1}, false},
 		{"complex yaml", args{[]byte(complexYaml)}, complexYamlResult, false},
 		{"complex json", args{[]byte(complexJson)}, complexYamlResult, false},
+		{"include tag", args{[]byte("a: !include a.yml\nb: !include [b.yml, c.toml]")}, map[string]interface{}{
+			"a": map[string]interface{}{"$include": "a.yml"},
+			"b": map[string]interface{}{"$include": []interface{}{"b.yml", "c.toml"}},
+		}, false},
 	}
 	s := YamlStrategy{}
 	for _, tt := range tests {
@@ -70,3 +75,104 @@ func Test_Yaml(t *testing.T) {
 		})
 	}
 }
+
+func Test_YamlPositions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    models.PositionMap
+		wantErr bool
+	}{
+		{"empty data", nil, nil, true},
+		{"invalid data", []byte("foobar´?"), nil, true},
+		{"complex yaml", []byte(complexYaml), models.PositionMap{
+			"a":   {Line: 2, Column: 1},
+			"c":   {Line: 3, Column: 1},
+			"c.d": {Line: 4, Column: 3},
+			"c.e": {Line: 5, Column: 3},
+			"f":   {Line: 6, Column: 1},
+		}, false},
+		{"complex json", []byte(complexJson), models.PositionMap{
+			"a":   {Line: 3, Column: 2},
+			"c":   {Line: 4, Column: 2},
+			"c.d": {Line: 5, Column: 3},
+			"c.e": {Line: 6, Column: 3},
+			"f":   {Line: 8, Column: 2},
+		}, false},
+		{"docs", []byte("# the a\n# of a\na: 1\nb: # the b\n  # c of b\n  c: x # more\n  d: 2 # the d\n"), models.PositionMap{
+			"a":   {Line: 3, Column: 1, Doc: "the a of a"},
+			"b":   {Line: 4, Column: 1, Doc: "the b"},
+			"b.c": {Line: 6, Column: 3, Doc: "c of b"},
+			"b.d": {Line: 7, Column: 3, Doc: "the d"},
+		}, false},
+	}
+	s := YamlStrategy{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Positions(tt.data)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_YamlErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantLine int
+		wantMsg  string
+	}{
+		{"unclosed sequence", "a: 1\nb: [\n", 2, "line 2: did not find expected node content"},
+		{"invalid indentation", "a: 1\n b: 2\n", 2, "line 2: mapping values are not allowed in this context"},
+		{"not a map", "foobar", 1, "line 1: cannot unmarshal !!str `foobar` into map[string]interface {}"},
+	}
+	s := YamlStrategy{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Parse([]byte(tt.data))
+			require.Error(t, err)
+			require.IsType(t, &ParseError{}, err)
+			assert.Equal(t, tt.wantLine, err.(*ParseError).Line)
+			assert.Equal(t, tt.wantMsg, err.Error())
+		})
+	}
+}
+
+func Test_YamlDocuments(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []Document
+		wantErr string
+	}{
+		{"empty data", "", nil, "Empty data"},
+		{"single document", "a: 1\nenv: x", []Document{
+			{Index: 1, Data: map[string]interface{}{"a": 1, "env": "x"}, Positions: models.PositionMap{"a": {Line: 1, Column: 1}, "env": {Line: 2, Column: 1}}},
+		}, ""},
+		{"multiple documents", "env: a\nb: 1\n---\nenv: c\nb: 2\n", []Document{
+			{Index: 1, Env: "a", Data: map[string]interface{}{"b": 1}, Positions: models.PositionMap{"b": {Document: 1, Line: 2, Column: 1}}},
+			{Index: 2, Env: "c", Data: map[string]interface{}{"b": 2}, Positions: models.PositionMap{"b": {Document: 2, Line: 5, Column: 1}}},
+		}, ""},
+		{"missing env", "env: a\nb: 1\n---\nb: 2\n", nil, "line 3:1: document 2: Document does not declare its 'env'"},
+		{"invalid document", "env: a\n---\nb: [\n", nil, "line 3: document 2: did not find expected node content"},
+		{"not a map", "env: a\n---\nfoobar\n", nil, "line 3: document 2: cannot unmarshal !!str `foobar` into map[string]interface {}"},
+	}
+	s := YamlStrategy{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.ParseDocuments([]byte(tt.data))
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.wantErr, err.Error())
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
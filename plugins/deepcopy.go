@@ -0,0 +1,262 @@
+package plugins
+
+import (
+	"io"
+	"text/template"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+type deepcopyPlugin struct {
+	s   models.SchemaMap
+	tpl *template.Template
+}
+
+var (
+	deepcopy = deepcopyPlugin{
+		s: models.SchemaMap{},
+		tpl: template.Must(template.
+			New("deepcopy").
+			Funcs(funcs).
+			Parse(`// Change is a field, which differs between two configs, see Config.Diff
+type Change struct {
+	// Path is the dotted path of the field, e.g. 'server.port'
+	Path string
+	Old  interface{}
+	New  interface{}
+}
+
+// Clone returns a deep copy of c, which shares no slices or maps with c
+func (c *Config) Clone() *Config {
+	copied := *c
+{{- range $_, $v := .}}{{if and (not $v.IsStruct) (hasPrefix $v.Content "[]")}}
+{{- if eq $v.Content "[]interface {}"}}
+	copied.{{makePath $v}} = cloneSlice(c.{{makePath $v}})
+{{- else if eq $v.Content "[]map[string]interface {}"}}
+	copied.{{makePath $v}} = cloneMapSlice(c.{{makePath $v}})
+{{- else}}
+	copied.{{makePath $v}} = append(c.{{makePath $v}}[:0:0], c.{{makePath $v}}...)
+{{- end}}
+{{- end}}{{end}}
+	return &copied
+}
+
+// Equal returns whether all fields of c and other are equal, see Diff
+func (c *Config) Equal(other *Config) bool {
+	return len(c.Diff(other)) == 0
+}
+
+// Diff returns the changes of all fields, which differ between c (old) and other (new),
+// ordered by their paths. Nil and empty slices are considered equal
+func (c *Config) Diff(other *Config) []Change {
+	changes := []Change{}
+{{- range $_, $v := .}}{{if not $v.IsStruct}}
+{{- if hasPrefix $v.Content "[]"}}
+	if !equalValue(c.{{makePath $v}}, other.{{makePath $v}}) {
+{{- else}}
+	if c.{{makePath $v}} != other.{{makePath $v}} {
+{{- end}}
+		changes = append(changes, Change{"{{makeSubstPath $v}}", c.{{makePath $v}}, other.{{makePath $v}}})
+	}
+{{- end}}{{end}}
+	return changes
+}
+
+// cloneValue returns a deep copy of v, if it is a slice or a map
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		return cloneSlice(v)
+	case map[string]interface{}:
+		return cloneMap(v)
+	case []map[string]interface{}:
+		return cloneMapSlice(v)
+	}
+	return v
+}
+
+// cloneSlice returns a deep copy of s
+func cloneSlice(s []interface{}) []interface{} {
+	if s == nil {
+		return nil
+	}
+	copied := make([]interface{}, len(s))
+	for i, v := range s {
+		copied[i] = cloneValue(v)
+	}
+	return copied
+}
+
+// cloneMap returns a deep copy of m
+func cloneMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		copied[k] = cloneValue(v)
+	}
+	return copied
+}
+
+// cloneMapSlice returns a deep copy of s
+func cloneMapSlice(s []map[string]interface{}) []map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+	copied := make([]map[string]interface{}, len(s))
+	for i, m := range s {
+		copied[i] = cloneMap(m)
+	}
+	return copied
+}
+
+// equalValue returns whether a and b are deeply equal,
+// both have to be of a type used by the fields of Config
+func equalValue(a, b interface{}) bool {
+	switch a := a.(type) {
+	case []string:
+		b, ok := b.([]string)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []int64:
+		b, ok := b.([]int64)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []uint64:
+		b, ok := b.([]uint64)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []float64:
+		b, ok := b.([]float64)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []bool:
+		b, ok := b.([]bool)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if a[i] != b[i] {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalValue(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case []map[string]interface{}:
+		b, ok := b.([]map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalValue(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			if w, found := b[k]; !found || !equalValue(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+`))}
+)
+
+func init() {
+	// "register" plugin
+	Plugins["deepcopy"] = &deepcopy
+}
+
+// Name returns the name of the plugin
+func (p *deepcopyPlugin) Name() string {
+	return "deepcopy"
+}
+
+// Description returns what the plugin generates
+func (p *deepcopyPlugin) Description() string {
+	return "generates Clone, Equal and Diff, which deep copy and compare configs"
+}
+
+// Configure configures the plugin, which has no options
+func (p *deepcopyPlugin) Configure(options map[string]string) error {
+	return noOptions(p.Name(), options)
+}
+
+// Imports returns no imports, as the generated code uses none
+func (p *deepcopyPlugin) Imports() []string {
+	return nil
+}
+
+// Dependencies returns no dependencies
+func (p *deepcopyPlugin) Dependencies() []string {
+	return nil
+}
+
+// InitCall returns false, as nothing is to be called on init
+func (p *deepcopyPlugin) InitCall() (InitCall, bool) {
+	return InitCall{}, false
+}
+
+// Methods returns the exported methods of Config generated by the plugin
+func (p *deepcopyPlugin) Methods() []string {
+	return []string{"Clone", "Equal", "Diff"}
+}
+
+// SetSchemaMap sets the schema to be used when WriteTo is called
+func (p *deepcopyPlugin) SetSchemaMap(s models.SchemaMap) {
+	p.s = s
+}
+
+// WriteTo performs the acutal writing to a buffer (or io.Writer).
+// For this plugin, the template is simply "rendered" into the writer.
+func (p *deepcopyPlugin) WriteTo(w io.Writer) (l int64, err error) {
+	err = p.tpl.Execute(w, p.s)
+	return
+}
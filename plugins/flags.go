@@ -0,0 +1,147 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+type flagsPlugin struct {
+	s      models.SchemaMap
+	tpl    *template.Template
+	naming EnvNaming
+}
+
+var (
+	flags = flagsPlugin{
+		s: models.SchemaMap{},
+		tpl: template.Must(template.
+			New("flags").
+			Funcs(funcs).
+			Parse(`// BindFlags registers a flag for every field of c, e.g. '-server.port', with the
+// current value of the field as default. Parsing fs sets the fields, so flags override
+// env vars, if BindFlags is called after init. Slices are given as JSON arrays like
+// env vars, a leading '+' appends to the default. Durations are not supported, as
+// the schema has no duration type: values like '5s' are strings
+func (c *Config) BindFlags(fs *flag.FlagSet) {
+{{- range $k, $v := .Schema}}{{if not $v.IsStruct}}
+{{- $name := $.Naming.DotName $v}}{{$usage := index $.Usages $k}}
+{{- if eq $v.Content "string"}}
+	fs.StringVar(&c.{{makePath $v}}, "{{$name}}", c.{{makePath $v}}, {{printf "%q" $usage}})
+{{- else if eq $v.Content "bool"}}
+	fs.BoolVar(&c.{{makePath $v}}, "{{$name}}", c.{{makePath $v}}, {{printf "%q" $usage}})
+{{- else if eq $v.Content "int64"}}
+	fs.Int64Var(&c.{{makePath $v}}, "{{$name}}", c.{{makePath $v}}, {{printf "%q" $usage}})
+{{- else if eq $v.Content "uint64"}}
+	fs.Uint64Var(&c.{{makePath $v}}, "{{$name}}", c.{{makePath $v}}, {{printf "%q" $usage}})
+{{- else if eq $v.Content "float64"}}
+	fs.Float64Var(&c.{{makePath $v}}, "{{$name}}", c.{{makePath $v}}, {{printf "%q" $usage}})
+{{- else}}
+	fs.Var(&flagValue{&c.{{makePath $v}}, func(s string) error {
+		return parse{{title (renameSlice $v.Content)}}(s, &c.{{makePath $v}})
+	}}, "{{$name}}", {{printf "%q" $usage}})
+{{- end}}
+{{- end}}{{end}}
+}
+
+// flagValue is a flag.Value for fields, which are parsed like env vars
+type flagValue struct {
+	field interface{}
+	parse func(string) error
+}
+
+// String returns the field as JSON
+func (v *flagValue) String() string {
+	if v == nil || v.field == nil {
+		return ""
+	}
+	b, err := json.Marshal(v.field)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Set parses s into the field
+func (v *flagValue) Set(s string) error {
+	return v.parse(s)
+}
+`))}
+)
+
+func init() {
+	// "register" plugin
+	Plugins["flags"] = &flags
+}
+
+// Name returns the name of the plugin
+func (p *flagsPlugin) Name() string {
+	return "flags"
+}
+
+// Description returns what the plugin generates
+func (p *flagsPlugin) Description() string {
+	return "generates BindFlags, which registers a command-line flag for every field (optional), durations are bound as strings"
+}
+
+// Optional returns true, as the generated code adds to the API of Config
+func (p *flagsPlugin) Optional() bool {
+	return true
+}
+
+// Configure configures the plugin. Available options are:
+// 'snake': whether to convert camelCase keys, e.g. '-long_desc.en' instead of '-longdesc.en'
+func (p *flagsPlugin) Configure(options map[string]string) error {
+	p.naming = EnvNaming{}
+	for k, v := range options {
+		if k != "snake" {
+			return fmt.Errorf("Plugin '%s' has no option '%s'", p.Name(), k)
+		}
+		if _, err := p.naming.SetOption(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Imports returns the packages used by the generated code
+func (p *flagsPlugin) Imports() []string {
+	return []string{"encoding/json", "flag"}
+}
+
+// Dependencies returns update_from_env, whose parse functions are used for slices
+func (p *flagsPlugin) Dependencies() []string {
+	return []string{"update_from_env"}
+}
+
+// InitCall returns false, as nothing is to be called on init
+func (p *flagsPlugin) InitCall() (InitCall, bool) {
+	return InitCall{}, false
+}
+
+// SetSchemaMap sets the schema to be used when WriteTo is called
+func (p *flagsPlugin) SetSchemaMap(s models.SchemaMap) {
+	p.s = s
+}
+
+// WriteTo performs the acutal writing to a buffer (or io.Writer).
+// The usage of each flag is the doc of its field followed by the
+// env var, which sets the field, too
+func (p *flagsPlugin) WriteTo(w io.Writer) (l int64, err error) {
+	usages := map[string]string{}
+	for k, s := range p.s {
+		usage := fmt.Sprintf("(env %s)", updateFromEnv.naming.EnvName(s))
+		if s.Doc != "" {
+			usage = s.Doc + " " + usage
+		}
+		usages[k] = usage
+	}
+	err = p.tpl.Execute(w, struct {
+		Schema models.SchemaMap
+		Naming EnvNaming
+		Usages map[string]string
+	}{p.s, p.naming, usages})
+	return
+}
@@ -0,0 +1,100 @@
+package plugins
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+var (
+	sliceMatcher = regexp.MustCompile(`^\[\](\w+)`)
+)
+
+var (
+	// funcs are the helper functions available in all plugin templates
+	funcs = template.FuncMap{
+		"upper":     strings.ToUpper,
+		"lower":     strings.ToLower,
+		"title":     strings.Title,
+		"hasPrefix": strings.HasPrefix,
+		// Remove root (usually "Config_") from env var name
+		"cleanPrefixEnv": func(s string) string {
+			return strings.Join(strings.Split(s, "_")[1:], "_")
+		},
+		// A_B to a.b
+		"dotPath": func(s string) string {
+			return strings.ReplaceAll(s, "_", ".")
+		},
+		// Convert a schema entry (or its path) to a Config path, e.g. 'Db.Uri'
+		"makePath": func(s interface{}) string {
+			return schemaOf(s).FieldPath()
+		},
+		// Convert a schema entry (or its path) to a substitution path, e.g. 'db.uri'
+		"makeSubstPath": func(s interface{}) string {
+			return schemaOf(s).DotPath()
+		},
+		// Substitute []*type* with *type*Slice
+		"renameSlice": renameSlice,
+	}
+)
+
+// renameSlice substitutes []*type* with *type*Slice, e.g. 'int64Slice' for '[]int64'
+func renameSlice(s string) string {
+	if found := sliceMatcher.FindStringSubmatch(s); len(found) > 0 {
+		return found[1] + "Slice"
+	}
+	return s
+}
+
+// schemaOf returns s, if it is a schema entry, or the schema entry of the path s,
+// whose keys are derived from it, as templates of user-defined plugins may pass paths
+func schemaOf(s interface{}) models.Schema {
+	if schema, ok := s.(models.Schema); ok {
+		return schema
+	}
+	path, _ := s.(string)
+	return models.Schema{Path: path}
+}
+
+// schemaStruct is a struct type of the schema with its fields
+type schemaStruct struct {
+	Type   string
+	Fields []schemaField
+}
+
+// schemaField is a field of a struct type
+type schemaField struct {
+	Name     string
+	IsStruct bool
+	Schema   models.Schema
+}
+
+// structsOf returns all struct types of the schema with their fields, sorted by their names
+func structsOf(schema models.SchemaMap) []schemaStruct {
+	fields := map[string][]schemaField{}
+	for _, s := range schema {
+		keys := s.Segments()
+		if len(keys) == 0 {
+			continue
+		}
+		// the type of the parent is named like its path without underscores, e.g. 'ConfigDb'
+		root := strings.SplitN(s.Path, "_", 2)[0]
+		parent := root
+		for _, k := range keys[:len(keys)-1] {
+			parent += strings.Replace(strings.Title(k), "_", "", -1)
+		}
+		fields[parent] = append(fields[parent], schemaField{Name: strings.Title(keys[len(keys)-1]), IsStruct: s.IsStruct, Schema: s})
+	}
+	structs := []schemaStruct{}
+	for k, s := range schema {
+		if s.IsStruct {
+			sort.Slice(fields[k], func(i, j int) bool { return fields[k][i].Name < fields[k][j].Name })
+			structs = append(structs, schemaStruct{Type: k, Fields: fields[k]})
+		}
+	}
+	sort.Slice(structs, func(i, j int) bool { return structs[i].Type < structs[j].Type })
+	return structs
+}
@@ -0,0 +1,160 @@
+package plugins
+
+import (
+	"io"
+	"text/template"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+type holderPlugin struct {
+	s   models.SchemaMap
+	tpl *template.Template
+}
+
+var (
+	holder = holderPlugin{
+		s: models.SchemaMap{},
+		tpl: template.Must(template.
+			New("holder").
+			Funcs(funcs).
+			Parse(`var (
+	// held holds the *Config returned by Load
+	held atomic.Value
+	// holderMu serializes Store, Update and Reload
+	holderMu sync.Mutex
+	// subscribers are the functions registered by OnChange
+	subscribers = map[int]func(old, new *Config){}
+	// nextSubscriber is the id of the next subscriber
+	nextSubscriber int
+)
+
+// Load returns the config stored by the last call of Store, Update or Init.
+// It is safe for concurrent use, but the returned config must not be modified,
+// use Update instead. Unlike Current, it is never modified in place
+func Load() *Config {
+	c, _ := held.Load().(*Config)
+	return c
+}
+
+// Store atomically replaces the config returned by Load by c and notifies all subscribers.
+// c must not be modified afterwards. Current is replaced by a copy of c, so it is in sync
+// with Load, but goroutines running concurrently to Store must use Load instead of Current
+func Store(c *Config) {
+	holderMu.Lock()
+	notify := store(c)
+	holderMu.Unlock()
+	notify()
+}
+
+// Update applies fn to a copy of the config returned by Load and stores the copy like Store.
+// Concurrent calls of Update and Store are applied one after another
+func Update(fn func(c *Config)) {
+	holderMu.Lock()
+	c := Load().Clone()
+	fn(c)
+	notify := store(c)
+	holderMu.Unlock()
+	notify()
+}
+
+// OnChange registers fn, which is called with the old and the new config after
+// every Store, Update, Reload or Init. fn is called synchronously by the changing
+// goroutine, after the change is visible to Load, so it may call Store, Update or the
+// returned function, which unregisters fn. Subscribers of concurrent changes may be
+// called concurrently, but old and new of each call are consecutive configs
+func OnChange(fn func(old, new *Config)) (cancel func()) {
+	holderMu.Lock()
+	defer holderMu.Unlock()
+	id := nextSubscriber
+	nextSubscriber++
+	subscribers[id] = fn
+	return func() {
+		holderMu.Lock()
+		defer holderMu.Unlock()
+		delete(subscribers, id)
+	}
+}
+
+// store stores c, replaces Current by a copy of c and returns the function notifying
+// the current subscribers, which has to be called after unlocking holderMu.
+// holderMu has to be locked
+func store(c *Config) (notify func()) {
+	old := Load()
+	held.Store(c)
+	Current = c.Clone()
+	ids := make([]int, 0, len(subscribers))
+	for id := range subscribers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	notified := make([]func(old, new *Config), len(ids))
+	for i, id := range ids {
+		notified[i] = subscribers[id]
+	}
+	return func() {
+		for _, fn := range notified {
+			fn(old, c)
+		}
+	}
+}
+
+// storeInHolder stores a copy of c, so that Init makes its result available by Load.
+// Configs other than Current, e.g. ones built by plugins, are not stored
+func (c *Config) storeInHolder() {
+	if c == Current {
+		Store(c.Clone())
+	}
+}
+`))}
+)
+
+func init() {
+	// "register" plugin
+	Plugins["holder"] = &holder
+}
+
+// Name returns the name of the plugin
+func (p *holderPlugin) Name() string {
+	return "holder"
+}
+
+// Description returns what the plugin generates
+func (p *holderPlugin) Description() string {
+	return "generates Load, Store, Update and OnChange for concurrency-safe access to the config"
+}
+
+// Configure configures the plugin, which has no options
+func (p *holderPlugin) Configure(options map[string]string) error {
+	return noOptions(p.Name(), options)
+}
+
+// Imports returns the packages used by the generated code
+func (p *holderPlugin) Imports() []string {
+	return []string{"sort", "sync", "sync/atomic"}
+}
+
+// Dependencies returns deepcopy, whose Clone is used to copy the config
+func (p *holderPlugin) Dependencies() []string {
+	return []string{"deepcopy"}
+}
+
+// InitCall returns the method to be called on init, which stores
+// the config after all other plugins have been applied
+func (p *holderPlugin) InitCall() (InitCall, bool) {
+	return InitCall{Method: "storeInHolder", Phase: PhaseHolder}, true
+}
+
+// SetSchemaMap sets the schema to be used when WriteTo is called
+func (p *holderPlugin) SetSchemaMap(s models.SchemaMap) {
+	p.s = s
+}
+
+// WriteTo performs the acutal writing to a buffer (or io.Writer).
+// For this plugin, the template is simply "rendered" into the writer.
+func (p *holderPlugin) WriteTo(w io.Writer) (l int64, err error) {
+	err = p.tpl.Execute(w, struct {
+		Schema models.SchemaMap
+	}{p.s})
+	return
+}
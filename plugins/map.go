@@ -16,30 +16,93 @@ var (
 	mapt = mapPlugin{
 		tpl: template.Must(template.
 			New("map").
-			Parse(`import "encoding/json"
+			Funcs(funcs).
+			Parse(`// AsMap converts the config into a map, keyed by the names of the fields.
+// Values are not copied, so slices are shared with the config
 func (c *Config) AsMap() map[string]interface{} {
-	marshaled, err := json.Marshal(c)
-	if err != nil {
-		return nil
+	return c.asMap()
+}
+{{range $_, $s := .Structs}}
+func (c *{{$s.Type}}) asMap() map[string]interface{} {
+	return map[string]interface{}{
+{{- range $_, $f := $s.Fields}}
+		"{{$f.Name}}": c.{{$f.Name}}{{if $f.IsStruct}}.asMap(){{end}},
+{{- end}}
+	}
+}
+{{end}}
+// Paths returns the dotted paths of all fields, which are no structs, e.g. 'server.port'
+func (c *Config) Paths() []string {
+	return []string{
+{{- range $_, $v := .Schema}}{{if not $v.IsStruct}}
+		"{{makeSubstPath $v}}",
+{{- end}}{{end}}
+	}
+}
+
+// GetPath returns the value of the field at the dotted path, e.g. 'server.port'
+func (c *Config) GetPath(path string) (interface{}, error) {
+	switch strings.ToLower(path) {
+{{- range $_, $v := .Schema}}{{if ne $v.Path "Config"}}
+	case "{{makeSubstPath $v}}":
+		return c.{{makePath $v}}, nil
+{{- end}}{{end}}
 	}
-	m := map[string]interface{}{}
-	if err := json.Unmarshal(marshaled, &m); err != nil {
+	return nil, fmt.Errorf("unknown path '%s'", path)
+}
+
+// SetPath sets the field at the dotted path, e.g. 'server.port', to value,
+// which has to be of the exact type of the field, e.g. int64 for 'server.port'
+func (c *Config) SetPath(path string, value interface{}) error {
+	switch strings.ToLower(path) {
+{{- range $k, $v := .Schema}}{{if ne $v.Path "Config"}}
+	case "{{makeSubstPath $v}}":
+		v, ok := value.({{if $v.IsStruct}}{{$k}}{{else}}{{$v.Content}}{{end}})
+		if !ok {
+			return fmt.Errorf("invalid value %#v of type %T for path '%s', expected {{if $v.IsStruct}}{{$k}}{{else}}{{$v.Content}}{{end}}", value, value, path)
+		}
+		c.{{makePath $v}} = v
 		return nil
+{{- end}}{{end}}
 	}
-	return m
+	return fmt.Errorf("unknown path '%s'", path)
 }
 `))}
 )
 
 func init() {
 	// "register" plugin
-	Plugins["99_map"] = &mapt
+	Plugins["map"] = &mapt
+}
+
+// Name returns the name of the plugin
+func (p *mapPlugin) Name() string {
+	return "map"
+}
+
+// Description returns what the plugin generates
+func (p *mapPlugin) Description() string {
+	return "generates AsMap, Paths, GetPath and SetPath for dynamic access to the config"
+}
+
+// Configure configures the plugin, which has no options
+func (p *mapPlugin) Configure(options map[string]string) error {
+	return noOptions(p.Name(), options)
+}
+
+// Imports returns the packages used by the generated code
+func (p *mapPlugin) Imports() []string {
+	return []string{"fmt", "strings"}
+}
+
+// Dependencies returns no dependencies
+func (p *mapPlugin) Dependencies() []string {
+	return nil
 }
 
-// GetInitCall returns the availibility and the string of the
-// function to be called on init
-func (p *mapPlugin) GetInitCall() (string, bool) {
-	return "", false
+// InitCall returns false, as nothing is to be called on init
+func (p *mapPlugin) InitCall() (InitCall, bool) {
+	return InitCall{}, false
 }
 
 // SetSchemaMap sets the schema to be used when WriteTo is called
@@ -50,6 +113,9 @@ func (p *mapPlugin) SetSchemaMap(s models.SchemaMap) {
 // WriteTo performs the acutal writing to a buffer (or io.Writer).
 // For this plugin, the template is simply "rendered" into the writer.
 func (p *mapPlugin) WriteTo(w io.Writer) (l int64, err error) {
-	err = p.tpl.Execute(w, nil)
+	err = p.tpl.Execute(w, struct {
+		Schema  models.SchemaMap
+		Structs []schemaStruct
+	}{p.s, structsOf(p.s)})
 	return
 }
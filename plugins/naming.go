@@ -0,0 +1,103 @@
+package plugins
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+const (
+	defaultEnvSeparator = "_"
+)
+
+var (
+	underscores = regexp.MustCompile(`_+`)
+)
+
+// EnvNaming defines how the names of env vars are derived from the keys
+// of the schema, e.g. 'DB_URI' and 'db.uri' for 'Config_Db_Uri'
+type EnvNaming struct {
+	// Prefix of all env var names, e.g. 'APP_' for 'APP_DB_URI' and 'app.db.uri'
+	Prefix string
+	// Separator between the keys of env var names, '_' if empty
+	Separator string
+	// Snake converts camelCase keys to SCREAMING_SNAKE_CASE,
+	// e.g. 'LONG_DESC_EN' instead of 'LONGDESC_EN'
+	Snake bool
+}
+
+// SetOption sets the naming option k, which is one of 'prefix', 'separator' or 'snake'.
+// It returns false, if k is no naming option
+func (n *EnvNaming) SetOption(k string, v string) (bool, error) {
+	switch k {
+	case "prefix":
+		n.Prefix = v
+	case "separator":
+		n.Separator = v
+	case "snake":
+		snake, err := strconv.ParseBool(v)
+		if err != nil {
+			return true, fmt.Errorf("Invalid value '%s' of option 'snake', expected a bool", v)
+		}
+		n.Snake = snake
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// EnvName returns the env var name of the schema entry, e.g. 'APP_DB_URI' for 'Config_Db_Uri'.
+// Underscores within keys are kept, e.g. 'DB__DB_NAME' for 'db.db_name' with separator '__'
+func (n EnvNaming) EnvName(s models.Schema) string {
+	sep := n.Separator
+	if sep == "" {
+		sep = defaultEnvSeparator
+	}
+	keys := n.keys(s)
+	for i, k := range keys {
+		keys[i] = strings.ToUpper(k)
+	}
+	return n.Prefix + strings.Join(keys, sep)
+}
+
+// DotName returns the dotted env var name of the schema entry, e.g. 'app.db.uri' for 'Config_Db_Uri'
+func (n EnvNaming) DotName(s models.Schema) string {
+	keys := n.keys(s)
+	for i, k := range keys {
+		keys[i] = strings.ToLower(k)
+	}
+	return strings.ToLower(underscores.ReplaceAllString(n.Prefix, ".")) + strings.Join(keys, ".")
+}
+
+// keys returns the keys of the schema entry
+func (n EnvNaming) keys(s models.Schema) []string {
+	keys := append([]string{}, s.Segments()...)
+	if n.Snake {
+		for i, k := range keys {
+			keys[i] = snake(k)
+		}
+	}
+	return keys
+}
+
+// snake converts camelCase into snake_case, keeping acronyms
+// together, e.g. 'apiURLPath' into 'api_URL_Path'
+func snake(s string) string {
+	runes := []rune(s)
+	b := &strings.Builder{}
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package plugins_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/plugins"
+)
+
+func Test_EnvNaming(t *testing.T) {
+	tests := []struct {
+		name    string
+		naming  plugins.EnvNaming
+		schema  models.Schema
+		wantEnv string
+		wantDot string
+	}{
+		{"default", plugins.EnvNaming{}, models.Schema{Path: "Config_Db_Uri"}, "DB_URI", "db.uri"},
+		{"camelCase", plugins.EnvNaming{}, models.Schema{Path: "Config_LongDesc_En"}, "LONGDESC_EN", "longdesc.en"},
+		{"prefix", plugins.EnvNaming{Prefix: "APP_"}, models.Schema{Path: "Config_Db_Uri"}, "APP_DB_URI", "app.db.uri"},
+		{"separator", plugins.EnvNaming{Separator: "__"}, models.Schema{Path: "Config_Db_Uri"}, "DB__URI", "db.uri"},
+		{"snake", plugins.EnvNaming{Snake: true}, models.Schema{Path: "Config_LongDesc_En"}, "LONG_DESC_EN", "long_desc.en"},
+		{"snake with acronyms", plugins.EnvNaming{Snake: true}, models.Schema{Path: "Config_ApiURLPath_V2Key"}, "API_URL_PATH_V2_KEY", "api_url_path.v2_key"},
+		{"underscore keys", plugins.EnvNaming{Separator: "__"}, models.Schema{Path: "Config_Db_Db_name", Keys: []string{"db", "db_name"}}, "DB__DB_NAME", "db.db_name"},
+		{"snake underscore keys", plugins.EnvNaming{Snake: true}, models.Schema{Path: "Config_Db_Db_nameX", Keys: []string{"db", "db_nameX"}}, "DB_DB_NAME_X", "db.db_name_x"},
+		{"all", plugins.EnvNaming{Prefix: "MYSVC__", Separator: "__", Snake: true}, models.Schema{Path: "Config_LongDesc_En"}, "MYSVC__LONG_DESC__EN", "mysvc.long_desc.en"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantEnv, tt.naming.EnvName(tt.schema))
+			assert.Equal(t, tt.wantDot, tt.naming.DotName(tt.schema))
+		})
+	}
+}
+
+func Test_EnvNaming_SetOption(t *testing.T) {
+	n := plugins.EnvNaming{}
+	for k, v := range map[string]string{"prefix": "APP_", "separator": "__", "snake": "true"} {
+		ok, err := n.SetOption(k, v)
+		assert.True(t, ok, k)
+		assert.NoError(t, err, k)
+	}
+	assert.Equal(t, plugins.EnvNaming{Prefix: "APP_", Separator: "__", Snake: true}, n)
+
+	ok, err := n.SetOption("snake", "maybe")
+	assert.True(t, ok)
+	assert.Error(t, err)
+
+	ok, err = n.SetOption("nope", "")
+	assert.False(t, ok)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,297 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+const (
+	defaultOverlayEnv = "CONFIG_OVERLAY"
+)
+
+type overlayPlugin struct {
+	s   models.SchemaMap
+	tpl *template.Template
+	env string
+}
+
+var (
+	overlay = overlayPlugin{
+		s:   models.SchemaMap{},
+		env: defaultOverlayEnv,
+		tpl: template.Must(template.
+			New("overlay").
+			Funcs(funcs).
+			Parse(`// overlayEnv is the env var, which holds the path of an overlay file loaded on init
+const overlayEnv = {{printf "%q" .Env}}
+
+// LoadOverlay merges the config file at path over the Current config, see Config.LoadOverlay
+func LoadOverlay(path string) error {
+	return Current.LoadOverlay(path)
+}
+
+// LoadOverlay merges the config file at path (yaml, json, toml or .env) over c.
+// All keys of the file must be defined by the schema and all values must
+// match the types of their fields, otherwise c is left unchanged
+func (c *Config) LoadOverlay(path string) error {
+	strategy, found := parsers.StrategyFor(path)
+	if !found {
+		return fmt.Errorf("overlay '%s': unsupported file type", path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("overlay '%s': %v", path, err)
+	}
+	values, err := strategy.Parse(data)
+	if err != nil {
+		return fmt.Errorf("overlay '%s': %v", path, err)
+	}
+	merged := *c
+	if errs := merged.mergeOverlay(path, "", values); len(errs) > 0 {
+		return fmt.Errorf("overlay '%s': %v", path, Errors(errs))
+	}
+	*c = merged
+	return nil
+}
+
+// LoadOverlayFromEnv merges the overlay file given by the env var {{.Env}} over c, if set
+func (c *Config) LoadOverlayFromEnv() error {
+	if path := os.Getenv(overlayEnv); path != "" {
+		return c.LoadOverlay(path)
+	}
+	return nil
+}
+
+// mergeOverlay sets all fields of c given by values of the overlay file,
+// prefix is their dotted path
+func (c *Config) mergeOverlay(file string, prefix string, values map[string]interface{}) []error {
+	errs := []error{}
+	keys := []string{}
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := values[k]
+		path := strings.ToLower(k)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		switch path {
+{{- range $_, $v := .Schema}}{{if ne $v.Path "Config"}}
+		case "{{makeSubstPath $v}}":
+{{- if $v.IsStruct}}
+			if sub, ok := v.(map[string]interface{}); ok {
+				errs = append(errs, c.mergeOverlay(file, path, sub)...)
+			} else {
+				errs = append(errs, fmt.Errorf("invalid value %#v of '%s', expected struct", v, path))
+			}
+{{- else}}
+			if !setOverlayValue(&c.{{makePath $v}}, v) {
+				errs = append(errs, fmt.Errorf("invalid value %#v of '%s', expected {{$v.Content}}", v, path))
+			} else {
+				recordSource(c, path, fmt.Sprintf("overlay '%s'", file))
+			}
+{{- end}}
+{{- end}}{{end}}
+		default:
+			errs = append(errs, fmt.Errorf("unknown key '%s'", path))
+		}
+	}
+	return errs
+}
+
+// setOverlayValue sets the field f to v, if v can be converted into the type of f
+func setOverlayValue(f interface{}, v interface{}) bool {
+	switch f := f.(type) {
+	case *string:
+		s, ok := v.(string)
+		if ok {
+			*f = s
+		}
+		return ok
+	case *bool:
+		b, ok := v.(bool)
+		if ok {
+			*f = b
+		}
+		return ok
+	case *int64:
+		i, ok := overlayInt64(v)
+		if ok {
+			*f = i
+		}
+		return ok
+	case *uint64:
+		if u, isUint := v.(uint64); isUint {
+			*f = u
+			return true
+		}
+		i, ok := overlayInt64(v)
+		if ok && i >= 0 {
+			*f = uint64(i)
+		}
+		return ok && i >= 0
+	case *float64:
+		switch n := v.(type) {
+		case float64:
+			*f = n
+			return true
+		default:
+			i, ok := overlayInt64(v)
+			if ok {
+				*f = float64(i)
+			}
+			return ok
+		}
+	case *[]interface{}:
+		l, ok := v.([]interface{})
+		if ok {
+			*f = l
+		}
+		return ok
+	}
+	l, ok := v.([]interface{})
+	if !ok {
+		return false
+	}
+	switch f := f.(type) {
+	case *[]string:
+		s := make([]string, len(l))
+		for i := range l {
+			if !setOverlayValue(&s[i], l[i]) {
+				return false
+			}
+		}
+		*f = s
+	case *[]bool:
+		s := make([]bool, len(l))
+		for i := range l {
+			if !setOverlayValue(&s[i], l[i]) {
+				return false
+			}
+		}
+		*f = s
+	case *[]int64:
+		s := make([]int64, len(l))
+		for i := range l {
+			if !setOverlayValue(&s[i], l[i]) {
+				return false
+			}
+		}
+		*f = s
+	case *[]uint64:
+		s := make([]uint64, len(l))
+		for i := range l {
+			if !setOverlayValue(&s[i], l[i]) {
+				return false
+			}
+		}
+		*f = s
+	case *[]float64:
+		s := make([]float64, len(l))
+		for i := range l {
+			if !setOverlayValue(&s[i], l[i]) {
+				return false
+			}
+		}
+		*f = s
+	case *[]map[string]interface{}:
+		s := make([]map[string]interface{}, len(l))
+		for i := range l {
+			m, ok := l[i].(map[string]interface{})
+			if !ok {
+				return false
+			}
+			s[i] = m
+		}
+		*f = s
+	default:
+		return false
+	}
+	return true
+}
+
+// overlayInt64 converts the integers returned by the parsers into int64
+func overlayInt64(v interface{}) (int64, bool) {
+	switch i := v.(type) {
+	case int:
+		return int64(i), true
+	case int64:
+		return i, true
+	case uint64:
+		return int64(i), i <= 1<<63-1
+	}
+	return 0, false
+}
+`))}
+)
+
+func init() {
+	// "register" plugin
+	Plugins["overlay"] = &overlay
+}
+
+// Name returns the name of the plugin
+func (p *overlayPlugin) Name() string {
+	return "overlay"
+}
+
+// Description returns what the plugin generates
+func (p *overlayPlugin) Description() string {
+	return "generates LoadOverlay, which merges a config file over the config at runtime (optional)"
+}
+
+// Optional returns true, as the generated code depends on genfig's parsers
+func (p *overlayPlugin) Optional() bool {
+	return true
+}
+
+// Configure configures the plugin. Available options are:
+// 'env': env var holding the path of the overlay file loaded on init, 'CONFIG_OVERLAY' by default
+func (p *overlayPlugin) Configure(options map[string]string) error {
+	p.env = defaultOverlayEnv
+	for k, v := range options {
+		switch k {
+		case "env":
+			p.env = v
+		default:
+			return fmt.Errorf("Plugin '%s' has no option '%s'", p.Name(), k)
+		}
+	}
+	return nil
+}
+
+// Imports returns the packages used by the generated code
+func (p *overlayPlugin) Imports() []string {
+	return []string{"fmt", "io/ioutil", "os", "sort", "strings", "github.com/thlcodes/genfig/parsers"}
+}
+
+// Dependencies returns no dependencies
+func (p *overlayPlugin) Dependencies() []string {
+	return nil
+}
+
+// InitCall returns the method to be called on init, which loads the
+// overlay file given by the env var before env vars are applied
+func (p *overlayPlugin) InitCall() (InitCall, bool) {
+	return InitCall{Method: "LoadOverlayFromEnv", Phase: PhaseOverlay, Returns: ReturnsError}, true
+}
+
+// SetSchemaMap sets the schema to be used when WriteTo is called
+func (p *overlayPlugin) SetSchemaMap(s models.SchemaMap) {
+	p.s = s
+}
+
+// WriteTo performs the acutal writing to a buffer (or io.Writer).
+// For this plugin, the template is simply "rendered" into the writer.
+func (p *overlayPlugin) WriteTo(w io.Writer) (l int64, err error) {
+	err = p.tpl.Execute(w, struct {
+		Schema models.SchemaMap
+		Env    string
+	}{p.s, p.env})
+	return
+}
@@ -1,17 +1,281 @@
 package plugins
 
 import (
+	"fmt"
 	"io"
+	"regexp"
+	"sort"
+	"strings"
 
 	"github.com/thlcodes/genfig/models"
 )
 
+// Phases of the built-in plugins. Init calls of plugins are called in the order
+// of their phase, but always after the init calls of the plugins they depend on
+const (
+	PhaseProvenance    = 10
+	PhaseOverlay       = 20
+	PhaseUpdateFromEnv = 30
+	PhaseDefault       = 50
+	PhaseSubstitute    = 80
+	PhaseHolder        = 100
+)
+
+// Types an init call can return
+const (
+	ReturnsNothing = ""
+	ReturnsError   = "error"
+	ReturnsErrors  = "[]error"
+	ReturnsBool    = "bool"
+)
+
+// InitCall describes a method of Config, which is called on init
+type InitCall struct {
+	// Method is the name of the method, which is called without arguments
+	Method string
+	// Phase orders the init calls, lower phases are called first
+	Phase int
+	// Returns is the type the method returns, one of the Returns* constants.
+	// Returned errors or false are reported instead of being discarded
+	Returns string
+}
+
+// Validate checks whether the init call can be generated
+func (c InitCall) Validate() error {
+	if !methodRegex.MatchString(c.Method) {
+		return fmt.Errorf("Invalid init method '%s'", c.Method)
+	}
+	switch c.Returns {
+	case ReturnsNothing, ReturnsError, ReturnsErrors, ReturnsBool:
+		return nil
+	default:
+		return fmt.Errorf("Invalid return type '%s' of init method '%s', expected one of '%s', '%s', '%s' or nothing", c.Returns, c.Method, ReturnsError, ReturnsErrors, ReturnsBool)
+	}
+}
+
+var (
+	methodRegex = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+)
+
 // Plugin interface
 type Plugin interface {
 	io.WriterTo
+	// Name returns the unique name of the plugin, e.g. 'update_from_env'
+	Name() string
+	// Description returns a short description of what the plugin generates
+	Description() string
+	// Configure resets the plugin's options to their defaults and applies the given ones.
+	// Unknown options result in an error
+	Configure(options map[string]string) error
+	// Imports returns the packages used by the generated code, either as 'path' or as 'name path'.
+	// The writer merges them into one import block, leaving out unused ones
+	Imports() []string
+	// Dependencies returns the names of the plugins, the generated code relies on
+	Dependencies() []string
 	SetSchemaMap(models.SchemaMap)
-	GetInitCall() (string, bool)
+	// InitCall returns the method to be called on init, if any
+	InitCall() (InitCall, bool)
+}
+
+// OptionalPlugin is implemented by plugins, which are only selected, if enabled explicitly,
+// e.g. because the generated code has additional dependencies
+type OptionalPlugin interface {
+	Optional() bool
+}
+
+// FieldsPlugin is implemented by plugins, whose generated code needs additional
+// unexported fields of Config, e.g. 'sources *valueSources'
+type FieldsPlugin interface {
+	Fields() []string
+}
+
+// MethodsPlugin is implemented by plugins, which generate exported methods of Config,
+// e.g. 'Clone'. Top-level keys of the configs must not be named like them
+type MethodsPlugin interface {
+	Methods() []string
+}
+
+// StructMethodsPlugin is implemented by plugins, which generate exported methods of Config
+// and of all its nested structs, e.g. 'String'. No key of the configs must be named like them
+type StructMethodsPlugin interface {
+	StructMethods() []string
+}
+
+// SourcesPlugin is implemented by plugins, which need the sources of all values,
+// i.e. the config files and lines they were read from, keyed by the names of the envs
+// (as in Envs, e.g. 'Production') and the dotted paths of the fields
+type SourcesPlugin interface {
+	SetSources(sources map[string]map[string]string)
+}
+
+// ReferencesPlugin is implemented by plugins, which need the references of the non-string
+// fields of the env configs (e.g. 'port: ${PORT}'), which are written as zero values,
+// keyed by the names of the envs (as in Envs, e.g. 'Production') and the dotted paths of the fields
+type ReferencesPlugin interface {
+	SetReferences(references map[string]map[string]string)
+}
+
+// ConstrainedFile is a file, which a plugin writes in addition to its own one,
+// but which is only built if its build constraint is satisfied,
+// e.g. because the generated code uses packages of newer Go versions
+type ConstrainedFile struct {
+	// Name is appended to the name of the plugin's file, e.g. 'slog' for 'plugin_redact_slog.go'
+	Name string
+	// Constraint is the build constraint, e.g. 'go1.21'
+	Constraint string
+	// Imports returns the packages used by the file, like Plugin.Imports
+	Imports []string
+}
+
+// ConstrainedFilesPlugin is implemented by plugins, which write additional files
+// with build constraints. The files are the same, regardless of the configuration
+type ConstrainedFilesPlugin interface {
+	ConstrainedFiles() []ConstrainedFile
+	// WriteConstrainedTo writes the content of the file with the given name
+	WriteConstrainedTo(w io.Writer, name string) error
 }
 
+// Set of plugins, keyed by their names
+type Set map[string]Plugin
+
 // Plugins hold the available plugins
-var Plugins = map[string]Plugin{}
+var Plugins = Set{}
+
+// Find returns the plugin registered with the given name
+func Find(name string) (Plugin, bool) {
+	return Plugins.Find(name)
+}
+
+// Names returns the sorted names of all registered plugins
+func Names() []string {
+	return Plugins.Names()
+}
+
+// Select returns the registered plugins matching enabled (all non-optional ones, if empty)
+// without the disabled ones. Unknown names result in an error
+func Select(enabled []string, disabled []string) (Set, error) {
+	return Plugins.Select(enabled, disabled)
+}
+
+// Find returns the plugin of the set with the given name
+func (s Set) Find(name string) (Plugin, bool) {
+	p, found := s[name]
+	return p, found
+}
+
+// Names returns the sorted names of all plugins of the set
+func (s Set) Names() []string {
+	names := []string{}
+	for n := range s {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Select returns the plugins of the set matching enabled (all non-optional ones, if empty)
+// without the disabled ones. Unknown names result in an error
+func (s Set) Select(enabled []string, disabled []string) (Set, error) {
+	selected := Set{}
+	if len(enabled) == 0 {
+		for n, p := range s {
+			if o, ok := p.(OptionalPlugin); !ok || !o.Optional() {
+				selected[n] = p
+			}
+		}
+	}
+	for _, n := range enabled {
+		p, found := s.Find(n)
+		if !found {
+			return nil, fmt.Errorf("Unknown plugin '%s', available are: %s", n, strings.Join(s.Names(), ", "))
+		}
+		selected[n] = p
+	}
+	for _, n := range disabled {
+		if _, found := s.Find(n); !found {
+			return nil, fmt.Errorf("Unknown plugin '%s', available are: %s", n, strings.Join(s.Names(), ", "))
+		}
+		delete(selected, n)
+	}
+	return selected, nil
+}
+
+// With returns a new set containing the plugins of both sets.
+// Plugins with the same name result in an error
+func (s Set) With(other Set) (Set, error) {
+	merged := Set{}
+	for n, p := range s {
+		merged[n] = p
+	}
+	for n, p := range other {
+		if _, found := s.Find(n); found {
+			return nil, fmt.Errorf("Plugin '%s' does already exist", n)
+		}
+		merged[n] = p
+	}
+	return merged, nil
+}
+
+// Ordered returns all plugins of the set, each one after the plugins it depends on.
+// Otherwise they are ordered by the phase of their init call and by their name.
+// Dependencies on plugins not in the set and cyclic dependencies result in an error
+func (s Set) Ordered() ([]Plugin, error) {
+	for _, n := range s.Names() {
+		for _, d := range s[n].Dependencies() {
+			if _, found := s.Find(d); !found {
+				return nil, fmt.Errorf("Plugin '%s' depends on '%s', which is not selected", n, d)
+			}
+		}
+	}
+
+	ordered := []Plugin{}
+	done := map[string]bool{}
+	for len(ordered) < len(s) {
+		ready := []string{}
+		for _, n := range s.Names() {
+			if !done[n] && dependenciesDone(s[n], done) {
+				ready = append(ready, n)
+			}
+		}
+		if len(ready) == 0 {
+			pending := []string{}
+			for _, n := range s.Names() {
+				if !done[n] {
+					pending = append(pending, n)
+				}
+			}
+			return nil, fmt.Errorf("Cyclic plugin dependencies between %s", strings.Join(pending, ", "))
+		}
+		sort.SliceStable(ready, func(i, j int) bool {
+			return phase(s[ready[i]]) < phase(s[ready[j]])
+		})
+		done[ready[0]] = true
+		ordered = append(ordered, s[ready[0]])
+	}
+	return ordered, nil
+}
+
+func dependenciesDone(p Plugin, done map[string]bool) bool {
+	for _, d := range p.Dependencies() {
+		if !done[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// phase returns the phase of the init call of p, PhaseDefault if it has none
+func phase(p Plugin) int {
+	if c, has := p.InitCall(); has {
+		return c.Phase
+	}
+	return PhaseDefault
+}
+
+// noOptions is the Configure implementation for plugins without any options
+func noOptions(name string, options map[string]string) error {
+	for k := range options {
+		return fmt.Errorf("Plugin '%s' has no option '%s'", name, k)
+	}
+	return nil
+}
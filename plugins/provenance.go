@@ -0,0 +1,173 @@
+package plugins
+
+import (
+	"io"
+	"text/template"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+type provenancePlugin struct {
+	s       models.SchemaMap
+	sources map[string]map[string]string
+	tpl     *template.Template
+}
+
+var (
+	provenance = provenancePlugin{
+		s:       models.SchemaMap{},
+		sources: map[string]map[string]string{},
+		tpl: template.Must(template.
+			New("provenance").
+			Funcs(funcs).
+			Parse(`// envSources are the sources of the values of all envs by their dotted paths,
+// i.e. the config files and lines they were read from when generating
+var envSources = map[*Config]map[string]string{
+{{- range $env, $sources := .Sources}}
+	&Envs.{{$env}}: {
+	{{- range $path, $source := $sources}}
+		{{printf "%q" $path}}: {{printf "%q" $source}},
+	{{- end}}
+	},
+{{- end}}
+}
+
+// valueSources holds the sources of the values of a config by their dotted paths.
+// The last source of a path is the one its value came from, the ones before were overridden.
+// It is never changed, but replaced, as copies of a config share it
+type valueSources map[string][]string
+
+// recordingSources enables recording the sources once
+var recordingSources sync.Once
+
+// recordSources is called on init before all other plugins. It resets the sources of c
+// to the ones of the config files and enables recording the sources set by the plugins
+func (c *Config) recordSources() {
+	c.sources = nil
+	recordingSources.Do(func() {
+		recordSource = recordValueSource
+	})
+}
+
+// recordValueSource adds source to the sources of the value at path
+func recordValueSource(c *Config, path string, source string) {
+	recorded := valueSources{}
+	if c.sources != nil {
+		for p, history := range *c.sources {
+			recorded[p] = history
+		}
+	}
+	history := c.sourceHistory(path)
+	recorded[path] = append(history[:len(history):len(history)], source)
+	c.sources = &recorded
+}
+
+// Source returns where the value of the field at the dotted path (e.g. 'db.uri') came from,
+// e.g. 'default.yml:12 -> env var DB_URI', or an empty string, if the path is unknown.
+// Besides the config files, the sources set by the plugins applied on c are recorded,
+// e.g. by 'update_from_env', 'overlay' and 'substitutor'
+func (c *Config) Source(path string) string {
+	return strings.Join(c.sourceHistory(strings.ToLower(path)), " -> ")
+}
+
+// Explain writes the values of all fields of c with their sources, one per line,
+// e.g. 'db.uri = mongodb://localhost (default.yml:12 -> env var DB_URI)'
+func (c *Config) Explain(w io.Writer) {
+{{- range $_, $v := .Schema}}{{if not $v.IsStruct}}
+	fmt.Fprintf(w, "%s = %v (%s)\n", "{{makeSubstPath $v}}", c.{{makePath $v}}, c.Source("{{makeSubstPath $v}}"))
+{{- end}}{{end}}
+}
+
+// sourceHistory returns the recorded sources of the value at path. If none were recorded,
+// it is the source of the config file of c, if c is the config of an env, otherwise the one
+// of the current env given by the env var 'ENV'
+func (c *Config) sourceHistory(path string) []string {
+	if c.sources != nil {
+		if history, found := (*c.sources)[path]; found {
+			return history
+		}
+	}
+	sources, found := envSources[c]
+	if !found {
+		env, _ := Get(os.Getenv("ENV"))
+		sources = envSources[env]
+	}
+	if source, found := sources[path]; found {
+		return []string{source}
+	}
+	return nil
+}
+`))}
+)
+
+func init() {
+	// "register" plugin
+	Plugins["provenance"] = &provenance
+}
+
+// Name returns the name of the plugin
+func (p *provenancePlugin) Name() string {
+	return "provenance"
+}
+
+// Description returns what the plugin generates
+func (p *provenancePlugin) Description() string {
+	return "generates Source and Explain, which tell where the values of a config came from (optional)"
+}
+
+// Optional returns true, as the generated code embeds the sources of all values
+// and records the sources of values set by other plugins
+func (p *provenancePlugin) Optional() bool {
+	return true
+}
+
+// Configure configures the plugin, which has no options
+func (p *provenancePlugin) Configure(options map[string]string) error {
+	return noOptions(p.Name(), options)
+}
+
+// Imports returns the packages used by the generated code
+func (p *provenancePlugin) Imports() []string {
+	return []string{"fmt", "io", "os", "strings", "sync"}
+}
+
+// Dependencies returns no dependencies
+func (p *provenancePlugin) Dependencies() []string {
+	return nil
+}
+
+// InitCall returns the call of recordSources, which enables recording
+// the sources before the other plugins are applied
+func (p *provenancePlugin) InitCall() (InitCall, bool) {
+	return InitCall{Method: "recordSources", Phase: PhaseProvenance}, true
+}
+
+// Fields returns the field holding the recorded sources of a config
+func (p *provenancePlugin) Fields() []string {
+	return []string{"sources *valueSources"}
+}
+
+// Methods returns the exported methods of Config generated by the plugin
+func (p *provenancePlugin) Methods() []string {
+	return []string{"Source", "Explain"}
+}
+
+// SetSources sets the sources of the values of all envs to be used when WriteTo is called
+func (p *provenancePlugin) SetSources(sources map[string]map[string]string) {
+	p.sources = sources
+}
+
+// SetSchemaMap sets the schema to be used when WriteTo is called
+func (p *provenancePlugin) SetSchemaMap(s models.SchemaMap) {
+	p.s = s
+}
+
+// WriteTo performs the acutal writing to a buffer (or io.Writer).
+// For this plugin, the template is simply "rendered" into the writer.
+func (p *provenancePlugin) WriteTo(w io.Writer) (l int64, err error) {
+	err = p.tpl.Execute(w, struct {
+		Schema  models.SchemaMap
+		Sources map[string]map[string]string
+	}{p.s, p.sources})
+	return
+}
@@ -0,0 +1,243 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+const (
+	// SecretMarker marks a key as secret in the comment documenting it in the default config,
+	// e.g. '# password of the admin @secret'
+	SecretMarker = "@secret"
+	// RedactedValue replaces the values of secret fields
+	RedactedValue = "[redacted]"
+)
+
+var (
+	// defaultSecretPatterns are the patterns of secret fields, if none are configured
+	defaultSecretPatterns = []string{"pass", "secret", "token", "key"}
+)
+
+type redactPlugin struct {
+	s   models.SchemaMap
+	tpl *template.Template
+	// patterns, one of which the dotted paths of secret fields contain
+	patterns []string
+	// fields are the dotted paths of additional secret fields
+	fields map[string]bool
+}
+
+var (
+	redact = redactPlugin{
+		s:        models.SchemaMap{},
+		patterns: defaultSecretPatterns,
+		fields:   map[string]bool{},
+		tpl: template.Must(template.
+			New("redact").
+			Funcs(funcs).
+			Parse(`// redactedValue replaces the values of secret fields, when a config is printed or logged
+const redactedValue = {{printf "%q" .Redacted}}
+{{range $_, $s := .Structs}}
+// String returns the fields of c like the verb %+v does, but with the values of all secret fields
+// replaced by redactedValue, so that c can be printed safely. Empty values are kept
+func (c {{$s.Type}}) String() string {
+	return fmt.Sprintf("{
+	{{- range $i, $f := $s.Fields}}{{if $i}} {{end}}{{$f.Name}}:%v{{end -}}
+	}"{{range $_, $f := $s.Fields}}, {{if index $.Secrets $f.Schema.Path}}redact(c.{{$f.Name}}){{else}}c.{{$f.Name}}{{end}}{{end}})
+}
+
+// GoString returns the fields of c like the verb %#v does, but with the values of all
+// secret fields replaced by redactedValue. Empty values are kept
+func (c {{$s.Type}}) GoString() string {
+	return fmt.Sprintf("%T{
+	{{- range $i, $f := $s.Fields}}{{if $i}}, {{end}}{{$f.Name}}:%#v{{end -}}
+	}", c{{range $_, $f := $s.Fields}}, {{if index $.Secrets $f.Schema.Path}}redact(c.{{$f.Name}}){{else}}c.{{$f.Name}}{{end}}{{end}})
+}
+{{end}}
+// redact returns redactedValue instead of v, unless v is empty
+func redact(v interface{}) interface{} {
+	empty := false
+	switch v := v.(type) {
+	case string:
+		empty = v == ""
+	case int64:
+		empty = v == 0
+	case uint64:
+		empty = v == 0
+	case float64:
+		empty = v == 0
+	case bool:
+		empty = !v
+	case []string:
+		empty = len(v) == 0
+	case []int64:
+		empty = len(v) == 0
+	case []uint64:
+		empty = len(v) == 0
+	case []float64:
+		empty = len(v) == 0
+	case []bool:
+		empty = len(v) == 0
+	case []interface{}:
+		empty = len(v) == 0
+	case []map[string]interface{}:
+		empty = len(v) == 0
+	}
+	if empty {
+		return v
+	}
+	return redactedValue
+}
+`))}
+	// redactSlogTpl is the template of the file generating LogValue
+	redactSlogTpl = template.Must(template.
+			New("redact_slog").
+			Funcs(funcs).
+			Parse(`{{range $i, $s := .Structs}}{{if $i}}
+{{end}}// LogValue returns the fields of c as group, keyed by their lowercase names,
+// with the values of all secret fields replaced by redactedValue
+func (c {{$s.Type}}) LogValue() slog.Value {
+	return slog.GroupValue(
+	{{- range $_, $f := $s.Fields}}
+		slog.Any("{{lower $f.Name}}", {{if index $.Secrets $f.Schema.Path}}redact(c.{{$f.Name}}){{else}}c.{{$f.Name}}{{end}}),
+	{{- end}}
+	)
+}
+{{end}}`))
+)
+
+func init() {
+	// "register" plugin
+	Plugins["redact"] = &redact
+}
+
+// IsSecret returns whether the field described by s is secret according to the options
+// of the plugin 'redact', i.e. it is marked by SecretMarker, it is one of the configured
+// fields or its dotted path contains one of the configured patterns (case-insensitive)
+func IsSecret(s models.Schema) bool {
+	return redact.isSecret(s)
+}
+
+// isSecret returns whether the field described by s is secret, see IsSecret
+func (p *redactPlugin) isSecret(s models.Schema) bool {
+	if strings.Contains(s.Doc, SecretMarker) {
+		return true
+	}
+	path := s.DotPath()
+	if p.fields[path] {
+		return true
+	}
+	for _, pattern := range p.patterns {
+		if strings.Contains(path, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// Name returns the name of the plugin
+func (p *redactPlugin) Name() string {
+	return "redact"
+}
+
+// Description returns what the plugin generates
+func (p *redactPlugin) Description() string {
+	return "generates String, GoString and LogValue (go1.21), which redact the values of secret fields"
+}
+
+// Configure configures the plugin. Available options are:
+// 'patterns': comma separated list of patterns, one of which the dotted paths of secret fields contain,
+// 'pass,secret,token,key' by default, none if empty.
+// 'fields': comma separated list of the dotted paths of additional secret fields, e.g. 'db.uri'
+func (p *redactPlugin) Configure(options map[string]string) error {
+	p.patterns, p.fields = defaultSecretPatterns, map[string]bool{}
+	for k, v := range options {
+		switch k {
+		case "patterns":
+			p.patterns = splitOption(v)
+		case "fields":
+			for _, f := range splitOption(v) {
+				p.fields[f] = true
+			}
+		default:
+			return fmt.Errorf("Plugin '%s' has no option '%s'", p.Name(), k)
+		}
+	}
+	return nil
+}
+
+// Imports returns the packages used by the generated code
+func (p *redactPlugin) Imports() []string {
+	return []string{"fmt"}
+}
+
+// Dependencies returns no dependencies
+func (p *redactPlugin) Dependencies() []string {
+	return nil
+}
+
+// InitCall returns false, as nothing is to be called on init
+func (p *redactPlugin) InitCall() (InitCall, bool) {
+	return InitCall{}, false
+}
+
+// ConstrainedFiles returns the file generating LogValue, as log/slog requires go1.21
+func (p *redactPlugin) ConstrainedFiles() []ConstrainedFile {
+	return []ConstrainedFile{{Name: "slog", Constraint: "go1.21", Imports: []string{"log/slog"}}}
+}
+
+// WriteConstrainedTo writes the file generating LogValue
+func (p *redactPlugin) WriteConstrainedTo(w io.Writer, name string) error {
+	if name != "slog" {
+		return fmt.Errorf("Plugin '%s' has no file '%s'", p.Name(), name)
+	}
+	return redactSlogTpl.Execute(w, struct {
+		Structs []schemaStruct
+		Secrets map[string]bool
+	}{structsOf(p.s), p.secrets()})
+}
+
+// StructMethods returns the exported methods generated for Config and all its nested structs
+func (p *redactPlugin) StructMethods() []string {
+	return []string{"String", "GoString", "LogValue"}
+}
+
+// SetSchemaMap sets the schema to be used when WriteTo is called
+func (p *redactPlugin) SetSchemaMap(s models.SchemaMap) {
+	p.s = s
+}
+
+// WriteTo performs the acutal writing to a buffer (or io.Writer).
+// For this plugin, the template is simply "rendered" into the writer.
+func (p *redactPlugin) WriteTo(w io.Writer) (l int64, err error) {
+	err = p.tpl.Execute(w, struct {
+		Redacted string
+		Structs  []schemaStruct
+		Secrets  map[string]bool
+	}{RedactedValue, structsOf(p.s), p.secrets()})
+	return
+}
+
+// secrets returns whether the fields of the schema are secret by their paths (e.g. 'Config_Db_Pass')
+func (p *redactPlugin) secrets() map[string]bool {
+	secrets := map[string]bool{}
+	for _, s := range p.s {
+		secrets[s.Path] = p.isSecret(s)
+	}
+	return secrets
+}
+
+// splitOption splits a comma separated option value into its lowercase entries, ignoring empty ones
+func splitOption(v string) []string {
+	entries := []string{}
+	for _, e := range strings.Split(v, ",") {
+		if e = strings.ToLower(strings.TrimSpace(e)); e != "" {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
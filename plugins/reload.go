@@ -0,0 +1,213 @@
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+const (
+	defaultReloadInterval = time.Second
+)
+
+type reloadPlugin struct {
+	s        models.SchemaMap
+	tpl      *template.Template
+	interval time.Duration
+}
+
+var (
+	reload = reloadPlugin{
+		s:        models.SchemaMap{},
+		interval: defaultReloadInterval,
+		tpl: template.Must(template.
+			New("reload").
+			Funcs(funcs).
+			Parse(`// defaultReloadInterval is the interval of polling the overlay file, if none is given
+const defaultReloadInterval = {{.Interval}} * time.Millisecond
+
+// ReloadOptions configure WatchReload
+type ReloadOptions struct {
+	// Interval of polling the overlay file, defaultReloadInterval if zero
+	Interval time.Duration
+	// OnReload is called with the dotted paths of all changed fields after a reload changed the config
+	OnReload func(changed []string)
+	// OnError is called with the error of a failed reload, the config returned by Load is kept then
+	OnError func(err error)
+}
+
+// Reload builds the config like Init does, i.e. by applying the overlay file,
+// the env vars, the substitution and all other plugins to the config of the
+// current env. If all succeed, it replaces the config returned by Load and returns
+// the dotted paths of all changed fields. Otherwise, e.g. if the env var 'ENV'
+// names an unknown env, it returns the errors and keeps the config.
+// Like Store, it replaces Current and notifies the subscribers
+func Reload() ([]string, error) {
+	env := os.Getenv("ENV")
+	c, found := Get(env)
+	if !found && env != "" {
+		return nil, fmt.Errorf("reload: unknown env '%s'", env)
+	}
+	next := c.Clone()
+	if errs := applyPlugins(next); len(errs) > 0 {
+		return nil, fmt.Errorf("reload: %v", Errors(errs))
+	}
+	holderMu.Lock()
+	changed := ChangedPaths(Load(), next)
+	notify := func() {}
+	if len(changed) > 0 {
+		notify = store(next)
+	}
+	holderMu.Unlock()
+	notify()
+	return changed, nil
+}
+
+// ChangedPaths returns the dotted paths of all fields, which differ between old and new
+func ChangedPaths(old, new *Config) []string {
+	if old == nil {
+		old = &Config{}
+	}
+	changed := []string{}
+	for _, change := range old.Diff(new) {
+		changed = append(changed, change.Path)
+	}
+	return changed
+}
+
+// WatchReload calls Reload on SIGHUP and whenever the overlay file given by
+// the env var {{.OverlayEnv}} changes, which is polled. The returned function stops
+// watching and waits for a running reload to finish
+func WatchReload(opts ReloadOptions) (stop func()) {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultReloadInterval
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	ticker := time.NewTicker(opts.Interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	last := overlayState()
+	go func() {
+		defer close(stopped)
+		defer ticker.Stop()
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+				last = overlayState()
+			case <-ticker.C:
+				state := overlayState()
+				if state == last {
+					continue
+				}
+				last = state
+			}
+			changed, err := Reload()
+			if err != nil {
+				if opts.OnError != nil {
+					opts.OnError(err)
+				}
+			} else if len(changed) > 0 && opts.OnReload != nil {
+				opts.OnReload(changed)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// overlayState describes the overlay file by its path, size and modification time
+func overlayState() string {
+	path := os.Getenv(overlayEnv)
+	if path == "" {
+		return ""
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return path
+	}
+	return fmt.Sprintf("%s|%d|%d", path, info.Size(), info.ModTime().UnixNano())
+}
+`))}
+)
+
+func init() {
+	// "register" plugin
+	Plugins["reload"] = &reload
+}
+
+// Name returns the name of the plugin
+func (p *reloadPlugin) Name() string {
+	return "reload"
+}
+
+// Description returns what the plugin generates
+func (p *reloadPlugin) Description() string {
+	return "generates Reload and WatchReload, which reload the config on SIGHUP or overlay file changes (optional)"
+}
+
+// Optional returns true, as the generated code starts a goroutine and handles SIGHUP, if used
+func (p *reloadPlugin) Optional() bool {
+	return true
+}
+
+// Configure configures the plugin. Available options are:
+// 'interval': default interval of polling the overlay file, e.g. '500ms', '1s' by default
+func (p *reloadPlugin) Configure(options map[string]string) error {
+	p.interval = defaultReloadInterval
+	for k, v := range options {
+		switch k {
+		case "interval":
+			d, err := time.ParseDuration(v)
+			if err != nil || d < time.Millisecond {
+				return fmt.Errorf("Plugin '%s' has an invalid interval '%s', expected a duration of at least 1ms", p.Name(), v)
+			}
+			p.interval = d
+		default:
+			return fmt.Errorf("Plugin '%s' has no option '%s'", p.Name(), k)
+		}
+	}
+	return nil
+}
+
+// Imports returns the packages used by the generated code
+func (p *reloadPlugin) Imports() []string {
+	return []string{"fmt", "os", "os/signal", "sync", "syscall", "time"}
+}
+
+// Dependencies returns overlay, whose file is watched, holder, which holds the
+// reloaded config, and deepcopy, whose Diff is used to find the changed paths
+func (p *reloadPlugin) Dependencies() []string {
+	return []string{"deepcopy", "holder", "overlay"}
+}
+
+// InitCall returns false, as nothing is to be called on init
+func (p *reloadPlugin) InitCall() (InitCall, bool) {
+	return InitCall{}, false
+}
+
+// SetSchemaMap sets the schema to be used when WriteTo is called
+func (p *reloadPlugin) SetSchemaMap(s models.SchemaMap) {
+	p.s = s
+}
+
+// WriteTo performs the acutal writing to a buffer (or io.Writer).
+// For this plugin, the template is simply "rendered" into the writer.
+func (p *reloadPlugin) WriteTo(w io.Writer) (l int64, err error) {
+	err = p.tpl.Execute(w, struct {
+		Interval   int64
+		OverlayEnv string
+	}{int64(p.interval / time.Millisecond), overlay.env})
+	return
+}
@@ -2,118 +2,401 @@ package plugins
 
 import (
 	"io"
-	"strings"
+	"regexp"
 	"text/template"
 
 	"github.com/thlcodes/genfig/models"
 )
 
 type substitutorPlugin struct {
-	s   models.SchemaMap
-	tpl *template.Template
+	s          models.SchemaMap
+	tpl        *template.Template
+	references map[string]map[string]string
 }
 
+var (
+	// referenceMatcher matches values, which consist of a single reference, e.g. '${PORT:-8080}'
+	referenceMatcher = regexp.MustCompile(`^\$\{[^{}]+\}$`)
+)
+
 var (
 	substitutor = substitutorPlugin{
-		s: models.SchemaMap{},
+		s:          models.SchemaMap{},
+		references: map[string]map[string]string{},
 		tpl: template.Must(template.
 			New("substitutor").
-			Funcs(template.FuncMap{
-				// Convert an env var name to a substitution path
-				"makeSubstPath": func(s string) string {
-					return strings.ToLower(strings.Join(strings.Split(s, "_")[1:], "."))
-				},
-				// Convert an env var name to a Config path
-				"makePath": func(s string) string {
-					return strings.Join(strings.Split(s, "_")[1:], ".")
-				},
-			}).
-			Parse(`import (
-	"strings"
-	"regexp"
-	"os"
-)
+			Funcs(funcs).
+			Parse(`// substitutionReferences holds the references of non-string fields of the env configs,
+// e.g. 'port: ${PORT}', by their dotted paths. The fields themselves hold zero values
+var substitutionReferences = map[*Config]map[string]string{
+{{- range $env, $refs := .References}}
+	&Envs.{{$env}}: {
+	{{- range $path, $ref := $refs}}
+		{{printf "%q" $path}}: {{printf "%q" $ref}},
+	{{- end}}
+	},
+{{- end}}
+}
 
-var _ = strings.Contains
+// substitutionPaths are the dotted paths of all string fields
+var substitutionPaths = []string{
+{{- range $_, $v := .Schema}}{{if eq $v.Content "string"}}
+	"{{makeSubstPath $v}}",
+{{- end}}{{end}}
+}
 
-const (
-	maxSubstitutionIteraions = 5
-)
+// substitutionEnvName matches the names of env vars, which can be referenced
+var substitutionEnvName = regexp.MustCompile(` + "`^\\w+$`" + `)
 
-var (
-	raw Config
-)
+// substitutionState is the state of the last substitution of a config.
+// It is never changed, but replaced, as copies of a config share it
+type substitutionState struct {
+	// raw is a copy of the config before the substitution was applied
+	raw *Config
+	// result is a copy of the config after the substitution was applied
+	result *Config
+	// overridden are the paths of non-string fields, which were changed after
+	// a substitution, so that their references are not applied anymore
+	overridden map[string]bool
+	errs       []error
+}
 
-// Substitute replaces all.
-// The return value informs, whether all substitutions could be
-// applied within {maxSubstitutionIteraions} or not
+// Substitute replaces all references in string fields by their values.
+// References are written as '${name}', where name is either the dotted path
+// of another field, e.g. '${server.port}', or an env var, e.g. '${HOME}'.
+// '${name:-default}' uses default, if name is unset or empty, '${name:?message}'
+// fails with message then. '$${' is kept as literal '${'.
+// Non-string fields of env configs can consist of a single reference,
+// e.g. 'port: ${PORT}', whose value has to match the type of the field.
+// If c was substituted before, the references of fields, which were not changed since,
+// are substituted again, while changed fields (e.g. by UpdateFromEnv) keep their values.
+// The return value informs, whether all substitutions could be applied,
+// the failures (e.g. unknown references, type mismatches or cycles) are returned by SubstitutionErrors
 func (c *Config) Substitute() bool {
-	c.ResetSubstitution()
+	return len(c.substitute()) == 0
+}
 
-	// backup the "raw" configuration
-	raw = *c
+// SubstitutionErrors returns the failures of the last substitution of c
+func (c *Config) SubstitutionErrors() []error {
+	if c.lastSubstitution == nil {
+		return nil
+	}
+	return append([]error{}, c.lastSubstitution.errs...)
+}
 
-	run := 0
-	for {
-		if run == maxSubstitutionIteraions {
-			return false
+// ResetSubstitution resets the configuration to the state,
+// before its last substitution was applied
+func (c *Config) ResetSubstitution() {
+	if c.lastSubstitution != nil {
+		*c = *c.lastSubstitution.raw.Clone()
+	}
+}
+
+// substitute applies the substitution to c and returns all failures
+func (c *Config) substitute() []error {
+	// backup the "raw" configuration, restoring the raw values of all fields,
+	// which were substituted last time and not changed since
+	state := &substitutionState{raw: c.Clone(), overridden: map[string]bool{}}
+	state.raw.lastSubstitution = nil
+	refs := c.substitutionReferences()
+	if last := c.lastSubstitution; last != nil {
+		changed := map[string]bool{}
+		for _, change := range last.result.Diff(c) {
+			changed[change.Path] = true
 		}
-		if c.substitute() == 0 {
-			return true
+		for path := range last.overridden {
+			state.overridden[path] = true
+		}
+		for _, change := range last.raw.Diff(last.result) {
+			if !changed[change.Path] {
+				value, _, _ := last.raw.substitutionValue(change.Path)
+				_ = state.raw.setSubstituted(change.Path, value)
+			}
+		}
+		for path := range refs {
+			if changed[path] {
+				state.overridden[path] = true
+			}
+		}
+		*c = *state.raw.Clone()
+	}
+
+	s := &substitution{
+		c:        c,
+		refs:     map[string]string{},
+		resolved: map[string]string{},
+		failed:   map[string]error{},
+	}
+	for path, ref := range refs {
+		if !state.overridden[path] {
+			s.refs[path] = ref
 		}
-		run += 1
 	}
+	paths := append([]string{}, substitutionPaths...)
+	refPaths := []string{}
+	for path := range s.refs {
+		refPaths = append(refPaths, path)
+	}
+	sort.Strings(refPaths)
+	reported := map[error]bool{}
+	for _, path := range append(paths, refPaths...) {
+		if _, err := s.resolve(path); err != nil && !reported[err] {
+			reported[err] = true
+			state.errs = append(state.errs, err)
+		}
+	}
+	state.result = c.Clone()
+	c.lastSubstitution = state
+	return state.errs
 }
 
-// ResetSubstitution resets the configuration to the state,
-// before the substitution was applied
-func (c *Config) ResetSubstitution() {
-	c = &raw
+// substitutionReferences returns the references of the non-string fields of c, which are
+// the ones of c, if it is an env config, or the ones of the env config selected by 'ENV'
+func (c *Config) substitutionReferences() map[string]string {
+	for _, env := range envMap {
+		if env == c {
+			return substitutionReferences[c]
+		}
+	}
+	env, _ := Get(os.Getenv("ENV"))
+	return substitutionReferences[env]
 }
 
-// substitute tries to replace all substitutions in strings
-func (c *Config) substitute() int {
-	cnt := 0
+// substitution holds the state of a single Substitute call
+type substitution struct {
+	c        *Config
+	refs     map[string]string
+	resolved map[string]string
+	failed   map[string]error
+	// stack holds the paths of the fields being resolved, to detect cycles
+	stack []string
+}
 
-	envReplacer := regexp.MustCompile(` + "`\\${\\w+}`" + `)
+// resolve substitutes the field at path, if necessary, and returns its value
+func (s *substitution) resolve(path string) (string, error) {
+	if value, done := s.resolved[path]; done {
+		return value, nil
+	}
+	if err, failed := s.failed[path]; failed {
+		return "", err
+	}
+	for i, p := range s.stack {
+		if p == path {
+			cycle := append(append([]string{}, s.stack[i:]...), path)
+			return "", substitutionError(path, "cycle %s", strings.Join(cycle, " -> "))
+		}
+	}
+	value, isString, _ := s.c.substitutionValue(path)
+	ref, isRef := s.refs[path]
+	if !isString && !isRef {
+		s.resolved[path] = value
+		return value, nil
+	}
+	if isRef {
+		value = ref
+	}
+	s.stack = append(s.stack, path)
+	expanded, err := s.expand(path, value)
+	s.stack = s.stack[:len(s.stack)-1]
+	if err == nil {
+		err = s.c.setSubstituted(path, expanded)
+	}
+	if err != nil {
+		s.failed[path] = err
+		return "", err
+	}
+	if expanded != value {
+		recordSource(s.c, path, fmt.Sprintf("substitution of '%s'", value))
+	}
+	s.resolved[path] = expanded
+	return expanded, nil
+}
 
-	r := strings.NewReplacer({{range $_, $v := .}}{{if eq $v.Content "string"}}
-		"${{"{"}}{{makeSubstPath $v.Path}}{{"}"}}", c.{{makePath $v.Path}},
-	{{end}}{{end}})
+// expand replaces all references in value, which is the value of the field at path
+func (s *substitution) expand(path, value string) (string, error) {
+	out := strings.Builder{}
+	for {
+		i := strings.Index(value, "${")
+		if i < 0 {
+			out.WriteString(value)
+			return out.String(), nil
+		}
+		if i > 0 && value[i-1] == '$' {
+			out.WriteString(value[:i-1] + "${")
+			value = value[i+2:]
+			continue
+		}
+		end := strings.Index(value[i:], "}")
+		if end < 0 {
+			return "", substitutionError(path, "missing '}' in '%s'", value[i:])
+		}
+		replaced, err := s.lookup(path, value[i+2:i+end])
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(value[:i] + replaced)
+		value = value[i+end+1:]
+	}
+}
 
-	{{range $_, $v := .}}{{if eq $v.Content "string"}}
-	if strings.Contains(c.{{makePath $v.Path}}, "${") {
-		cnt += 1
-		c.{{makePath $v.Path}} = r.Replace(c.{{makePath $v.Path}})
-		if !strings.Contains(c.{{makePath $v.Path}}, "${") {
-			cnt -= 1
-		} else {
-			c.{{makePath $v.Path}} = envReplacer.ReplaceAllStringFunc(c.{{makePath $v.Path}}, func(in string) (out string) {
-				out = in
-				envName := in[2:len(in)-1]
-				if envVal, found := os.LookupEnv(envName); found {
-					out = envVal
-				}
-				return
-			})
+// lookup returns the value of the reference expr, e.g. 'server.host' or 'PORT:-8080',
+// which is used by the field at path
+func (s *substitution) lookup(path, expr string) (string, error) {
+	name, op, arg := expr, "", ""
+	if i := strings.Index(expr, ":"); i >= 0 && i+1 < len(expr) && (expr[i+1] == '-' || expr[i+1] == '?') {
+		name, op, arg = expr[:i], expr[i:i+2], expr[i+2:]
+	}
+	value, found := "", false
+	if _, _, known := s.c.substitutionValue(name); known {
+		v, err := s.resolve(name)
+		if err != nil {
+			return "", err
 		}
+		value, found = v, true
+	} else if substitutionEnvName.MatchString(name) {
+		value, found = os.LookupEnv(name)
 	}
-	{{end}}{{end}}
+	if value != "" || (found && op == "") {
+		return value, nil
+	}
+	switch op {
+	case ":-":
+		return arg, nil
+	case ":?":
+		if arg == "" {
+			arg = "not set"
+		}
+		return "", substitutionError(path, "${%s}: %s", name, arg)
+	}
+	return "", substitutionError(path, "unknown reference ${%s}", name)
+}
+
+// substitutionError returns an error of the substitution of the field at path
+func substitutionError(path string, format string, args ...interface{}) error {
+	return fmt.Errorf("could not substitute '%s': %s", path, fmt.Sprintf(format, args...))
+}
 
-	return cnt
+// substitutionValue returns the value of the field at path as string, whether the field
+// is a string field and whether it can be referenced at all, i.e. is no slice
+func (c *Config) substitutionValue(path string) (value string, isString bool, known bool) {
+	switch path {
+{{- range $_, $v := .Schema}}{{if not $v.IsStruct}}
+{{- if eq $v.Content "string"}}
+	case "{{makeSubstPath $v}}":
+		return c.{{makePath $v}}, true, true
+{{- else if eq $v.Content "int64"}}
+	case "{{makeSubstPath $v}}":
+		return strconv.FormatInt(c.{{makePath $v}}, 10), false, true
+{{- else if eq $v.Content "uint64"}}
+	case "{{makeSubstPath $v}}":
+		return strconv.FormatUint(c.{{makePath $v}}, 10), false, true
+{{- else if eq $v.Content "float64"}}
+	case "{{makeSubstPath $v}}":
+		return strconv.FormatFloat(c.{{makePath $v}}, 'g', -1, 64), false, true
+{{- else if eq $v.Content "bool"}}
+	case "{{makeSubstPath $v}}":
+		return strconv.FormatBool(c.{{makePath $v}}), false, true
+{{- end}}
+{{- end}}{{end}}
+	}
+	return "", false, false
+}
+
+// setSubstituted sets the field at path to the substituted value,
+// which is parsed, if the field is no string field
+func (c *Config) setSubstituted(path string, value string) error {
+	switch path {
+{{- range $_, $v := .Schema}}{{if not $v.IsStruct}}
+{{- if eq $v.Content "string"}}
+	case "{{makeSubstPath $v}}":
+		c.{{makePath $v}} = value
+{{- else if eq $v.Content "int64"}}
+	case "{{makeSubstPath $v}}":
+		v, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return substitutionError(path, "invalid value '%s', expected int64", value)
+		}
+		c.{{makePath $v}} = v
+{{- else if eq $v.Content "uint64"}}
+	case "{{makeSubstPath $v}}":
+		v, err := strconv.ParseUint(value, 0, 64)
+		if err != nil {
+			return substitutionError(path, "invalid value '%s', expected uint64", value)
+		}
+		c.{{makePath $v}} = v
+{{- else if eq $v.Content "float64"}}
+	case "{{makeSubstPath $v}}":
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return substitutionError(path, "invalid value '%s', expected float64", value)
+		}
+		c.{{makePath $v}} = v
+{{- else if eq $v.Content "bool"}}
+	case "{{makeSubstPath $v}}":
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return substitutionError(path, "invalid value '%s', expected bool", value)
+		}
+		c.{{makePath $v}} = v
+{{- end}}
+{{- end}}{{end}}
+	}
+	return nil
 }
 `))}
 )
 
+// IsReference returns whether v is a string, which consists of a single reference
+// to be substituted, e.g. '${PORT:-8080}'. Such values are allowed for non-string
+// fields of env configs, if the substitutor is selected
+func IsReference(v interface{}) bool {
+	s, ok := v.(string)
+	return ok && referenceMatcher.MatchString(s)
+}
+
 func init() {
 	// "register" plugin
-	Plugins["80_substitutor"] = &substitutor
+	Plugins["substitutor"] = &substitutor
+}
+
+// Name returns the name of the plugin
+func (p *substitutorPlugin) Name() string {
+	return "substitutor"
+}
+
+// Description returns what the plugin generates
+func (p *substitutorPlugin) Description() string {
+	return "generates Substitute, which replaces ${...} references to other fields or env vars"
+}
+
+// Configure configures the plugin, which has no options
+func (p *substitutorPlugin) Configure(options map[string]string) error {
+	return noOptions(p.Name(), options)
+}
+
+// Imports returns the packages used by the generated code
+func (p *substitutorPlugin) Imports() []string {
+	return []string{"fmt", "os", "regexp", "sort", "strconv", "strings"}
+}
+
+// Dependencies returns deepcopy, whose Clone and Diff are used to backup the raw config
+func (p *substitutorPlugin) Dependencies() []string {
+	return []string{"deepcopy"}
+}
+
+// InitCall returns the method to be called on init, which reports all failures of Substitute
+func (p *substitutorPlugin) InitCall() (InitCall, bool) {
+	return InitCall{Method: "substitute", Phase: PhaseSubstitute, Returns: ReturnsErrors}, true
+}
+
+// Fields returns the field holding the state of the last substitution of a config
+func (p *substitutorPlugin) Fields() []string {
+	return []string{"lastSubstitution *substitutionState"}
 }
 
-// GetInitCall returns the availibility and the string of the
-// function to be called on init
-func (p *substitutorPlugin) GetInitCall() (string, bool) {
-	return "Current.Substitute()", true
+// SetReferences sets the references of the non-string fields of all envs to be used when WriteTo is called
+func (p *substitutorPlugin) SetReferences(references map[string]map[string]string) {
+	p.references = references
 }
 
 // SetSchemaMap sets the schema to be used when WriteTo is called
@@ -124,6 +407,9 @@ func (p *substitutorPlugin) SetSchemaMap(s models.SchemaMap) {
 // WriteTo performs the acutal writing to a buffer (or io.Writer).
 // For this plugin, the template is simply "rendered" into the writer.
 func (p *substitutorPlugin) WriteTo(w io.Writer) (l int64, err error) {
-	err = p.tpl.Execute(w, p.s)
+	err = p.tpl.Execute(w, struct {
+		Schema     models.SchemaMap
+		References map[string]map[string]string
+	}{p.s, p.references})
 	return
 }
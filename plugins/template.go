@@ -0,0 +1,195 @@
+package plugins
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/thlcodes/genfig/models"
+)
+
+const (
+	templateExt          = ".tmpl"
+	frontMatterDelimiter = "---"
+)
+
+var (
+	templateNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+)
+
+// frontMatter is the optional yaml header of a template plugin, e.g.
+//
+//	---
+//	description: generates Foo
+//	optional: true
+//	imports: [fmt, os]
+//	dependencies: [map]
+//	init: Foo
+//	phase: 40
+//	returns: error
+//	options:
+//	  bar: default
+//	---
+type frontMatter struct {
+	Description  string            `yaml:"description"`
+	Optional     bool              `yaml:"optional"`
+	Imports      []string          `yaml:"imports"`
+	Dependencies []string          `yaml:"dependencies"`
+	Init         string            `yaml:"init"`
+	Phase        *int              `yaml:"phase"`
+	Returns      string            `yaml:"returns"`
+	Options      map[string]string `yaml:"options"`
+}
+
+// templatePlugin is a user-defined plugin, loaded from a template file.
+// The template is rendered with the SchemaMap and has access to the same
+// helper functions as the built-in plugins, plus 'option' returning an option value.
+// Like the built-in ones, it renders the body of the file, the header with the
+// package clause is written by the generator
+type templatePlugin struct {
+	s       models.SchemaMap
+	tpl     *template.Template
+	name    string
+	meta    frontMatter
+	options map[string]string
+}
+
+// LoadTemplatePlugins loads all template plugins (*.tmpl) of dir
+func LoadTemplatePlugins(dir string) (Set, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+templateExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	loaded := Set{}
+	for _, f := range files {
+		p, err := loadTemplatePlugin(f)
+		if err != nil {
+			return nil, err
+		}
+		loaded[p.name] = p
+	}
+	return loaded, nil
+}
+
+func loadTemplatePlugin(f string) (*templatePlugin, error) {
+	name := strings.TrimSuffix(filepath.Base(f), templateExt)
+	if !templateNameRegex.MatchString(name) {
+		return nil, fmt.Errorf("%s: invalid plugin name '%s', only lower case letters, digits and '_' are allowed", f, name)
+	}
+	data, err := ioutil.ReadFile(f)
+	if err != nil {
+		return nil, err
+	}
+	p := &templatePlugin{name: name, options: map[string]string{}}
+	body := string(data)
+	if strings.HasPrefix(body, frontMatterDelimiter+"\n") {
+		parts := strings.SplitN(body[len(frontMatterDelimiter)+1:], "\n"+frontMatterDelimiter+"\n", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s: unterminated front matter", f)
+		}
+		if err := yaml.Unmarshal([]byte(parts[0]), &p.meta); err != nil {
+			return nil, fmt.Errorf("%s: invalid front matter: %v", f, err)
+		}
+		body = parts[1]
+	}
+	if c, has := p.InitCall(); has {
+		if err := c.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", f, err)
+		}
+	}
+	tplFuncs := template.FuncMap{
+		"option": func(k string) string {
+			return p.options[k]
+		},
+	}
+	for k, fn := range funcs {
+		tplFuncs[k] = fn
+	}
+	if p.tpl, err = template.New(name).Funcs(tplFuncs).Parse(body); err != nil {
+		return nil, fmt.Errorf("%s: %v", f, err)
+	}
+	if err := p.Configure(nil); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Name returns the name of the plugin, which is the name of its template file
+func (p *templatePlugin) Name() string {
+	return p.name
+}
+
+// Description returns the description declared in the front matter
+func (p *templatePlugin) Description() string {
+	if p.meta.Description == "" {
+		return "user-defined template plugin"
+	}
+	return p.meta.Description
+}
+
+// Configure configures the plugin. Available options are the ones
+// declared in the front matter, which also holds their defaults
+func (p *templatePlugin) Configure(options map[string]string) error {
+	p.options = map[string]string{}
+	for k, v := range p.meta.Options {
+		p.options[k] = v
+	}
+	for k, v := range options {
+		if _, declared := p.meta.Options[k]; !declared {
+			return fmt.Errorf("Plugin '%s' has no option '%s'", p.name, k)
+		}
+		p.options[k] = v
+	}
+	return nil
+}
+
+// Optional returns whether the plugin is only selected, if enabled explicitly
+func (p *templatePlugin) Optional() bool {
+	return p.meta.Optional
+}
+
+// Imports returns the imports declared in the front matter
+func (p *templatePlugin) Imports() []string {
+	return p.meta.Imports
+}
+
+// Dependencies returns the dependencies declared in the front matter
+func (p *templatePlugin) Dependencies() []string {
+	return p.meta.Dependencies
+}
+
+// InitCall returns the init call declared in the front matter, if any
+func (p *templatePlugin) InitCall() (InitCall, bool) {
+	if p.meta.Init == "" {
+		return InitCall{}, false
+	}
+	c := InitCall{Method: p.meta.Init, Phase: PhaseDefault, Returns: p.meta.Returns}
+	if p.meta.Phase != nil {
+		c.Phase = *p.meta.Phase
+	}
+	return c, true
+}
+
+// SetSchemaMap sets the schema to be used when WriteTo is called
+func (p *templatePlugin) SetSchemaMap(s models.SchemaMap) {
+	p.s = s
+}
+
+// WriteTo renders the template into the writer. The template is rendered into
+// a buffer first, so that nothing is written if it fails
+func (p *templatePlugin) WriteTo(w io.Writer) (l int64, err error) {
+	buf := &bytes.Buffer{}
+	if err = p.tpl.Execute(buf, p.s); err != nil {
+		return
+	}
+	return buf.WriteTo(w)
+}
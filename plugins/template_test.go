@@ -0,0 +1,100 @@
+package plugins_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/plugins"
+)
+
+func writeTemplates(t *testing.T, templates map[string]string) string {
+	dir, err := ioutil.TempDir("", "genfig")
+	require.NoError(t, err)
+	for name, content := range templates {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func Test_LoadTemplatePlugins(t *testing.T) {
+	tests := []struct {
+		name      string
+		templates map[string]string
+		wantNames []string
+		wantErr   bool
+	}{
+		{"empty", map[string]string{}, []string{}, false},
+		{"no front matter", map[string]string{"a.tmpl": "func A() {}\n"}, []string{"a"}, false},
+		{"with front matter", map[string]string{"a.tmpl": "---\ninit: A\nphase: 7\n---\nfunc (c *Config) A() {}\n"}, []string{"a"}, false},
+		{"other files are ignored", map[string]string{"a.tmpl": "", "b.txt": ""}, []string{"a"}, false},
+		{"invalid name", map[string]string{"My-Plugin.tmpl": ""}, nil, true},
+		{"unterminated front matter", map[string]string{"a.tmpl": "---\nphase: 7\n"}, nil, true},
+		{"invalid front matter", map[string]string{"a.tmpl": "---\nphase: [\n---\n"}, nil, true},
+		{"invalid init method", map[string]string{"a.tmpl": "---\ninit: Current.A()\n---\n"}, nil, true},
+		{"invalid init return type", map[string]string{"a.tmpl": "---\ninit: A\nreturns: int\n---\n"}, nil, true},
+		{"invalid template", map[string]string{"a.tmpl": "{{ .Foo "}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTemplates(t, tt.templates)
+			defer os.RemoveAll(dir)
+			got, err := plugins.LoadTemplatePlugins(dir)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantNames, got.Names())
+		})
+	}
+}
+
+func Test_TemplatePlugin(t *testing.T) {
+	got, err := plugins.LoadTemplatePlugins("../fixtures/plugins")
+	require.NoError(t, err)
+	p, found := got.Find("paths")
+	require.True(t, found)
+
+	assert.Equal(t, "paths", p.Name())
+	assert.Equal(t, "lists all config paths", p.Description())
+	assert.Equal(t, []string{"fmt"}, p.Imports())
+	assert.False(t, p.(plugins.OptionalPlugin).Optional())
+	assert.Empty(t, p.Dependencies())
+	call, has := p.InitCall()
+	assert.True(t, has)
+	assert.Equal(t, plugins.InitCall{Method: "PrintPaths", Phase: 95}, call)
+
+	s := models.SchemaMap{
+		"DbUri": models.Schema{Content: "string", Path: "Config_Db_Uri"},
+	}
+	p.SetSchemaMap(s)
+
+	buf := &bytes.Buffer{}
+	_, err = p.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `fmt.Println("config.db.uri")`)
+
+	require.NoError(t, p.Configure(map[string]string{"prefix": "app"}))
+	buf.Reset()
+	_, err = p.WriteTo(buf)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `fmt.Println("app.db.uri")`)
+
+	assert.Error(t, p.Configure(map[string]string{"nope": "x"}))
+}
+
+func Test_Set_With(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{"map.tmpl": ""})
+	defer os.RemoveAll(dir)
+	templates, err := plugins.LoadTemplatePlugins(dir)
+	require.NoError(t, err)
+	_, err = plugins.Plugins.With(templates)
+	assert.Error(t, err, "name conflicts with built-in plugin")
+}
@@ -15,9 +15,7 @@ var (
 	configTest = configTestPlugin{
 		tpl: template.Must(template.
 			New("configTest").
-			Parse(`import "testing"
-
-func Test_CurrentConfig(t *testing.T) {
+			Parse(`func Test_CurrentConfig(t *testing.T) {
 	if Current == nil {
 		t.Error("Current config is nil")
 	}
@@ -27,13 +25,37 @@ func Test_CurrentConfig(t *testing.T) {
 
 func init() {
 	// "register" plugin
-	Plugins["90_config_test"] = &configTest
+	Plugins["config_test"] = &configTest
+}
+
+// Name returns the name of the plugin
+func (p *configTestPlugin) Name() string {
+	return "config_test"
+}
+
+// Description returns what the plugin generates
+func (p *configTestPlugin) Description() string {
+	return "generates a test, which ensures that the current config is set"
+}
+
+// Configure configures the plugin, which has no options
+func (p *configTestPlugin) Configure(options map[string]string) error {
+	return noOptions(p.Name(), options)
+}
+
+// Imports returns the packages used by the generated code
+func (p *configTestPlugin) Imports() []string {
+	return []string{"testing"}
+}
+
+// Dependencies returns no dependencies
+func (p *configTestPlugin) Dependencies() []string {
+	return nil
 }
 
-// GetInitCall returns the availibility and the string of the
-// function to be called on init
-func (p *configTestPlugin) GetInitCall() (string, bool) {
-	return "", false
+// InitCall returns false, as nothing is to be called on init
+func (p *configTestPlugin) InitCall() (InitCall, bool) {
+	return InitCall{}, false
 }
 
 // SetSchemaMap sets the schema to be used when WriteTo is called
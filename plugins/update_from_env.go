@@ -1,220 +1,258 @@
 package plugins
 
 import (
+	"fmt"
 	"io"
-	"regexp"
 	"strings"
 	"text/template"
 
 	"github.com/thlcodes/genfig/models"
 )
 
-type updateFromEnvPlugin struct {
-	s   models.SchemaMap
-	tpl *template.Template
+// numberType is a numeric type of Go with its size in bits (0 for int and uint)
+type numberType struct {
+	Type string
+	Bits int
+}
+
+// sliceType is the type of slice elements, with the name used in parse{Name}Slice
+type sliceType struct {
+	Name string
+	Type string
 }
 
 var (
-	sliceMatcher = regexp.MustCompile(`^\[\](\w+)`)
+	intTypes   = []numberType{{"int", 0}, {"int8", 8}, {"int16", 16}, {"int32", 32}, {"int64", 64}}
+	uintTypes  = []numberType{{"uint", 0}, {"uint8", 8}, {"uint16", 16}, {"uint32", 32}, {"uint64", 64}}
+	floatTypes = []numberType{{"float32", 32}, {"float64", 64}}
+	sliceTypes = []sliceType{
+		{"String", "string"},
+		{"Int64", "int64"},
+		{"Uint64", "uint64"},
+		{"Float64", "float64"},
+		{"Bool", "bool"},
+		{"Interface", "interface{}"},
+		{"Map", "map[string]interface{}"},
+	}
 )
 
+type updateFromEnvPlugin struct {
+	s          models.SchemaMap
+	tpl        *template.Template
+	naming     EnvNaming
+	secretsDir string
+}
+
 var (
 	updateFromEnv = updateFromEnvPlugin{
 		s: models.SchemaMap{},
 		tpl: template.Must(template.
 			New("updateFromEnv").
-			Funcs(template.FuncMap{
-				"upper": strings.ToUpper,
-				"lower": strings.ToLower,
-				"title": strings.Title,
-				// Remove root (usually "Config_") from env var name
-				"cleanPrefixEnv": func(s string) string {
-					return strings.Join(strings.Split(s, "_")[1:], "_")
-				},
-				// A_B to a.b
-				"dotPath": func(s string) string {
-					return strings.ReplaceAll(s, "_", ".")
-				},
-				// Converte an env var name to a Config path
-				"makePath": func(s string) string {
-					return strings.Join(strings.Split(s, "_")[1:], ".")
-				},
-				// Substitute []*type* with *type*Slice
-				"renameSlice": func(s string) string {
-					if found := sliceMatcher.FindStringSubmatch(s); len(found) > 0 {
-						return found[1] + "Slice"
-					}
-					return s
-				},
-			}).
-			Parse(`import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"strconv"
-	"strings"
-)
-
-var (
-	_ = os.LookupEnv
-	_ = fmt.Sprintf
-	_ = json.Marshal
-)
+			Funcs(funcs).
+			Parse(`// secretsDir holds files named like the env vars, which contain their values
+const secretsDir = {{printf "%q" .SecretsDir}}
 
+// UpdateFromEnv overrides all fields, for which an env var is set.
+// If not, the value is read from the file given by the env var <NAME>_FILE
+// or from the file named like the env var in the secrets dir, if configured.
+// Values, which cannot be parsed into the type of their field, and files, which
+// cannot be read, are reported as errors, leaving the field unchanged
 func (c *Config) UpdateFromEnv() []error {
-	var val string
-	_ = val
-	var exists bool
-	_ = exists
-	var envs []string
-	_ = envs
-	var errors = []error{}
-{{range $_, $v := .}}{{if not $v.IsStruct}}
-	envs = []string{"{{dotPath (cleanPrefixEnv (lower $v.Path))}}", "{{cleanPrefixEnv (upper $v.Path)}}"}
-	for _, env := range envs {
-		if val, exists = os.LookupEnv(env); exists {
-			break
-		}
-	}
-	if exists { {{if eq $v.Content "string"}}
-		c.{{makePath $v.Path}} = val {{else}}
-		if err := parse{{title (renameSlice $v.Content)}}(val, &c.{{makePath $v.Path}}); err != nil {
-			errors = append(errors, fmt.Errorf("Genfig: could not parse {{$v.Content}} from {{upper $v.Path}} ('%s')\n", val))
+	errs := []error{}
+{{range $_, $v := .Schema}}{{if not $v.IsStruct}}
+	if src, val, exists, err := lookupEnv("{{$.Naming.DotName $v}}", "{{$.Naming.EnvName $v}}"); err != nil {
+		errs = append(errs, fmt.Errorf("could not read %s: %v", src, err))
+	} else if exists { {{if eq $v.Content "string"}}
+		c.{{makePath $v}} = val
+		recordSource(c, "{{makeSubstPath $v}}", src) {{else}}
+		if err = parse{{title (renameSlice $v.Content)}}(val, &c.{{makePath $v}}); err != nil {
+			errs = append(errs, envError(src, val, "{{$v.Content}}", err))
+		} else {
+			recordSource(c, "{{makeSubstPath $v}}", src)
 		} {{end}}
 	}
 {{end}}{{end}}
-	if len(errors) == 0 {
+	if len(errs) == 0 {
 		return nil
-	} else {
-		return errors
 	}
+	return errs
 }
 
-// these are wrappers, so that they can
-// a) be referenced easily be the code generator and
-// b) be replaces easily by you (or me)
-func parseInt64(s string, i *int64) (err error) {
-	if got, err := strconv.ParseInt(s, 10, 0); err == nil {
-		*i = got
+// lookupEnv returns the value of the first of the given env vars, which is set.
+// Otherwise, the value is read from the file given by <NAME>_FILE or from the
+// file named like the env var in the secrets dir. The source describes, where
+// the value was found
+func lookupEnv(names ...string) (src string, val string, exists bool, err error) {
+	for _, name := range names {
+		if val, exists = os.LookupEnv(name); exists {
+			return "env var " + name, val, true, nil
+		}
 	}
-	return
+	for _, name := range names {
+		if file, set := os.LookupEnv(name + "_FILE"); set {
+			val, err = readSecret(file)
+			return fmt.Sprintf("file '%s' (%s_FILE)", file, name), val, err == nil, err
+		}
+	}
+	if secretsDir == "" {
+		return "", "", false, nil
+	}
+	for _, name := range names {
+		file := filepath.Join(secretsDir, name)
+		if _, statErr := os.Stat(file); os.IsNotExist(statErr) {
+			continue
+		}
+		val, err = readSecret(file)
+		return fmt.Sprintf("file '%s'", file), val, err == nil, err
+	}
+	return "", "", false, nil
 }
 
-func parseFloat64(s string, f *float64) (err error) {
-	if got, err := strconv.ParseFloat(s, 0); err == nil {
-		*f = got
+// readSecret returns the content of file without leading and trailing white space
+func readSecret(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
 	}
-	return
+	return strings.TrimSpace(string(data)), nil
 }
 
-func parseBool(s string, b *bool) (err error) {
-	if got, err := strconv.ParseBool(s); err == nil {
-		*b = got
+// envError describes a value found in src, which could not be parsed into typ
+func envError(src string, val string, typ string, err error) error {
+	if numErr, ok := err.(*strconv.NumError); ok {
+		err = numErr.Err
 	}
-	return
+	return fmt.Errorf("invalid value '%s' of %s, expected %s: %v", val, src, typ, err)
 }
 
-func parseStringSlice(s string, a *[]string) (err error) {
-	add := false
-	if strings.HasPrefix(s, "+") {
-		add = true
-		s = s[1:]
-	}
-	tmp := []string{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
-	}
-	if add {
-		*a = append(*a, tmp...)
-	} else {
-		*a = tmp
+// these are wrappers, so that they can
+// a) be referenced easily be the code generator and
+// b) be replaces easily by you (or me).
+// Integers are parsed like Go literals, e.g. '0x1f', '0o17' or '1_000'
+{{- range $_, $t := .Ints}}
+
+func parse{{title $t.Type}}(s string, i *{{$t.Type}}) error {
+	got, err := strconv.ParseInt(s, 0, {{$t.Bits}})
+	if err != nil {
+		return err
 	}
-	return
+	*i = {{$t.Type}}(got)
+	return nil
 }
+{{- end}}
+{{- range $_, $t := .Uints}}
 
-func parseInt64Slice(s string, a *[]int64) (err error) {
-	add := false
-	if strings.HasPrefix(s, "+") {
-		add = true
-		s = s[1:]
+func parse{{title $t.Type}}(s string, i *{{$t.Type}}) error {
+	got, err := strconv.ParseUint(s, 0, {{$t.Bits}})
+	if err != nil {
+		return err
 	}
-	tmp := []int64{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
-	}
-	if add {
-		*a = append(*a, tmp...)
-	} else {
-		*a = tmp
-	}
-	return
+	*i = {{$t.Type}}(got)
+	return nil
 }
+{{- end}}
+{{- range $_, $t := .Floats}}
 
-func parseFloat64Slice(s string, a *[]float64) (err error) {
-	add := false
-	if strings.HasPrefix(s, "+") {
-		add = true
-		s = s[1:]
-	}
-	tmp := []float64{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
-	}
-	if add {
-		*a = append(*a, tmp...)
-	} else {
-		*a = tmp
+func parse{{title $t.Type}}(s string, f *{{$t.Type}}) error {
+	got, err := strconv.ParseFloat(s, {{$t.Bits}})
+	if err != nil {
+		return err
 	}
-	return
+	*f = {{$t.Type}}(got)
+	return nil
 }
+{{- end}}
+{{- if .Bool}}
 
-func parseInterfaceSlice(s string, a *[]interface{},) (err error) {
-	add := false
-	if strings.HasPrefix(s, "+") {
-		add = true
-		s = s[1:]
-	}
-	tmp := []interface{}{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
-	}
-	if add {
-		*a = append(*a, tmp...)
-	} else {
-		*a = tmp
+func parseBool(s string, b *bool) error {
+	got, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
 	}
-	return
+	*b = got
+	return nil
 }
+{{- end}}
+
+// Slices are parsed as JSON arrays, a leading '+' appends to the current slice
+{{- range $_, $t := .Slices}}
 
-func parseMapSlice(s string, a *[]map[string]interface{}) (err error) {
+func parse{{$t.Name}}Slice(s string, a *[]{{$t.Type}}) error {
 	add := false
 	if strings.HasPrefix(s, "+") {
 		add = true
 		s = s[1:]
 	}
-	tmp := []map[string]interface{}{}
-	if err = json.Unmarshal([]byte(s), &tmp); err != nil {
-		return
+	tmp := []{{$t.Type}}{}
+	if err := json.Unmarshal([]byte(s), &tmp); err != nil {
+		return err
 	}
 	if add {
 		*a = append(*a, tmp...)
 	} else {
 		*a = tmp
 	}
-	return
+	return nil
 }
+{{- end}}
 `))}
 )
 
 func init() {
 	// "register" plugin
-	Plugins["30_update_from_env"] = &updateFromEnv
+	Plugins["update_from_env"] = &updateFromEnv
+}
+
+// EnvNames returns the names of the env vars, which UpdateFromEnv reads the field of the
+// schema entry s from, according to the options of the plugin, e.g. 'db.uri' and 'DB_URI' for 'Config_Db_Uri'
+func EnvNames(s models.Schema) []string {
+	return []string{updateFromEnv.naming.DotName(s), updateFromEnv.naming.EnvName(s)}
+}
+
+// Name returns the name of the plugin
+func (p *updateFromEnvPlugin) Name() string {
+	return "update_from_env"
+}
+
+// Description returns what the plugin generates
+func (p *updateFromEnvPlugin) Description() string {
+	return "generates UpdateFromEnv, which overrides config fields by env vars"
+}
+
+// Configure configures the plugin. Available options are the ones of EnvNaming:
+// 'prefix': prefix of all env var names, e.g. 'APP_' for 'APP_DB_URI' and 'app.db.uri'
+// 'separator': separator between the keys of env var names, e.g. '__' for 'DB__URI'
+// 'snake': whether to convert camelCase keys, e.g. 'LONG_DESC_EN' instead of 'LONGDESC_EN'
+// Additionally, 'secrets_dir' is the directory holding files named like the env vars,
+// e.g. '/run/secrets/DB_PASS', which are read if neither the env var nor <NAME>_FILE is set
+func (p *updateFromEnvPlugin) Configure(options map[string]string) error {
+	p.naming = EnvNaming{}
+	p.secretsDir = ""
+	for k, v := range options {
+		if k == "secrets_dir" {
+			p.secretsDir = v
+		} else if ok, err := p.naming.SetOption(k, v); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("Plugin '%s' has no option '%s'", p.Name(), k)
+		}
+	}
+	return nil
+}
+
+// Imports returns the packages used by the generated code
+func (p *updateFromEnvPlugin) Imports() []string {
+	return []string{"encoding/json", "fmt", "io/ioutil", "os", "path/filepath", "strconv", "strings"}
+}
+
+// Dependencies returns no dependencies
+func (p *updateFromEnvPlugin) Dependencies() []string {
+	return nil
 }
 
-// GetInitCall returns the availibility and the string of the
-// function to be called on init
-func (p *updateFromEnvPlugin) GetInitCall() (string, bool) {
-	return "if errs := Current.UpdateFromEnv(); errs != nil {\n\tfmt.Println(errs)\n}", true
+// InitCall returns the method to be called on init
+func (p *updateFromEnvPlugin) InitCall() (InitCall, bool) {
+	return InitCall{Method: "UpdateFromEnv", Phase: PhaseUpdateFromEnv, Returns: ReturnsErrors}, true
 }
 
 // SetSchemaMap sets the schema to be used when WriteTo is called
@@ -224,7 +262,38 @@ func (p *updateFromEnvPlugin) SetSchemaMap(s models.SchemaMap) {
 
 // WriteTo performs the acutal writing to a buffer (or io.Writer).
 // For this plugin, the template is simply "rendered" into the writer.
+// Only the parse functions of the types of the schema are written
 func (p *updateFromEnvPlugin) WriteTo(w io.Writer) (l int64, err error) {
-	err = p.tpl.Execute(w, p.s)
+	used := map[string]bool{}
+	for _, s := range p.s {
+		if !s.IsStruct {
+			used[strings.Title(renameSlice(s.Content))] = true
+		}
+	}
+	numbers := func(types []numberType) []numberType {
+		filtered := []numberType{}
+		for _, t := range types {
+			if used[strings.Title(t.Type)] {
+				filtered = append(filtered, t)
+			}
+		}
+		return filtered
+	}
+	slices := []sliceType{}
+	for _, t := range sliceTypes {
+		if used[t.Name+"Slice"] {
+			slices = append(slices, t)
+		}
+	}
+	err = p.tpl.Execute(w, struct {
+		Schema     models.SchemaMap
+		Naming     EnvNaming
+		SecretsDir string
+		Ints       []numberType
+		Uints      []numberType
+		Floats     []numberType
+		Bool       bool
+		Slices     []sliceType
+	}{p.s, p.naming, p.secretsDir, numbers(intTypes), numbers(uintTypes), numbers(floatTypes), used["Bool"], slices})
 	return
 }
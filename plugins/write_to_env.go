@@ -1,8 +1,8 @@
 package plugins
 
 import (
+	"fmt"
 	"io"
-	"strings"
 	"text/template"
 
 	"github.com/thlcodes/genfig/models"
@@ -11,6 +11,8 @@ import (
 type writeToEnvPlugin struct {
 	s   models.SchemaMap
 	tpl *template.Template
+	// naming of the env vars, the one of update_from_env if nil
+	naming *EnvNaming
 }
 
 var (
@@ -18,58 +20,32 @@ var (
 		s: models.SchemaMap{},
 		tpl: template.Must(template.
 			New("writeToEnv").
-			Funcs(template.FuncMap{
-				"upper":     strings.ToUpper,
-				"lower":     strings.ToLower,
-				"hasPrefix": strings.HasPrefix,
-				// Remove root (usually "Config_") from env var name
-				"dotPath": func(s string) string {
-					return strings.ReplaceAll(s, "_", ".")
-				},
-				"cleanPrefixEnv": func(s string) string {
-					return strings.Join(strings.Split(s, "_")[1:], "_")
-				},
-				// Converte an env var name to a Config path
-				"makePath": func(s string) string {
-					return strings.Join(strings.Split(s, "_")[1:], ".")
-				},
-			}).
-			Parse(`import (
-	"fmt"
-	"os"
-	"io"
-	"fmt"
-	"encoding/json"
-)
-
-var (
-	_ = os.Setenv
-	_ = fmt.Sprintf
-	_ = json.Marshal
-)
-
-func (c *Config) WriteToEnv() {
+			Funcs(funcs).
+			Parse(`func (c *Config) WriteToEnv() {
 	var buf []byte
 	_ = buf
-{{range $_, $v := .}}{{if not $v.IsStruct}}
+{{range $_, $v := .Schema}}{{if not $v.IsStruct}}
 	{{if hasPrefix $v.Content "[]"}}
-	buf, _ = json.Marshal(c.{{makePath $v.Path}})
-	_ = os.Setenv("{{cleanPrefixEnv (upper $v.Path)}}", string(buf))
+	buf, _ = json.Marshal(c.{{makePath $v}})
+	_ = os.Setenv("{{$.Naming.EnvName $v}}", string(buf))
 	{{else}}
-	_ = os.Setenv("{{cleanPrefixEnv (upper $v.Path)}}", fmt.Sprintf("%v", c.{{makePath $v.Path}}))
+	_ = os.Setenv("{{$.Naming.EnvName $v}}", fmt.Sprintf("%v", c.{{makePath $v}}))
 	{{end}}
 {{end}}{{end}}
 }
 
+// PrintDebugEnvs prints the env vars WriteToEnv sets, with the values of secret fields redacted
 func (c *Config) PrintDebugEnvs(w io.Writer) {
 	var buf []byte
 	_ = buf
-{{range $_, $v := .}}{{if not $v.IsStruct}}
-	{{if hasPrefix $v.Content "[]"}}
-	buf, _ = json.Marshal(c.{{makePath $v.Path}})
-	fmt.Fprintf(w, "{{cleanPrefixEnv (upper $v.Path)}}/{{dotPath (cleanPrefixEnv (lower $v.Path))}}='%v'\n", string(buf))
+{{range $_, $v := .Schema}}{{if not $v.IsStruct}}
+	{{if index $.Secrets $v.Path}}
+	fmt.Fprintf(w, "{{$.Naming.EnvName $v}}/{{$.Naming.DotName $v}}='%v'\n", {{printf "%q" $.Redacted}})
+	{{else if hasPrefix $v.Content "[]"}}
+	buf, _ = json.Marshal(c.{{makePath $v}})
+	fmt.Fprintf(w, "{{$.Naming.EnvName $v}}/{{$.Naming.DotName $v}}='%v'\n", string(buf))
 	{{else}}
-	fmt.Fprintf(w, "{{cleanPrefixEnv (upper $v.Path)}}/{{dotPath (cleanPrefixEnv (lower $v.Path))}}='%v'\n", c.{{makePath $v.Path}})
+	fmt.Fprintf(w, "{{$.Naming.EnvName $v}}/{{$.Naming.DotName $v}}='%v'\n", c.{{makePath $v}})
 	{{end}}
 {{end}}{{end}}
 }
@@ -78,13 +54,57 @@ func (c *Config) PrintDebugEnvs(w io.Writer) {
 
 func init() {
 	// "register" plugin
-	Plugins["85_write_to_env"] = &writeToEnv
+	Plugins["write_to_env"] = &writeToEnv
+}
+
+// WriteToEnvName returns the name of the env var, which WriteToEnv sets for the field of
+// the schema entry s, according to the options of the plugin, e.g. 'DB_URI' for 'Config_Db_Uri'
+func WriteToEnvName(s models.Schema) string {
+	return writeToEnv.envNaming().EnvName(s)
 }
 
-// GetInitCall returns the availibility and the string of the
-// function to be called on init
-func (p *writeToEnvPlugin) GetInitCall() (string, bool) {
-	return "", false
+// Name returns the name of the plugin
+func (p *writeToEnvPlugin) Name() string {
+	return "write_to_env"
+}
+
+// Description returns what the plugin generates
+func (p *writeToEnvPlugin) Description() string {
+	return "generates WriteToEnv and PrintDebugEnvs, which export the config as env vars"
+}
+
+// Configure configures the plugin. Available options are the ones of update_from_env,
+// which are used by default, so that both plugins use the same env var names
+func (p *writeToEnvPlugin) Configure(options map[string]string) error {
+	p.naming = nil
+	if len(options) == 0 {
+		return nil
+	}
+	naming := EnvNaming{}
+	for k, v := range options {
+		if ok, err := naming.SetOption(k, v); err != nil {
+			return err
+		} else if !ok {
+			return fmt.Errorf("Plugin '%s' has no option '%s'", p.Name(), k)
+		}
+	}
+	p.naming = &naming
+	return nil
+}
+
+// Imports returns the packages used by the generated code
+func (p *writeToEnvPlugin) Imports() []string {
+	return []string{"encoding/json", "fmt", "io", "os"}
+}
+
+// Dependencies returns no dependencies
+func (p *writeToEnvPlugin) Dependencies() []string {
+	return nil
+}
+
+// InitCall returns false, as nothing is to be called on init
+func (p *writeToEnvPlugin) InitCall() (InitCall, bool) {
+	return InitCall{}, false
 }
 
 // SetSchemaMap sets the schema to be used when WriteTo is called
@@ -95,6 +115,24 @@ func (p *writeToEnvPlugin) SetSchemaMap(s models.SchemaMap) {
 // WriteTo performs the acutal writing to a buffer (or io.Writer).
 // For this plugin, the template is simply "rendered" into the writer.
 func (p *writeToEnvPlugin) WriteTo(w io.Writer) (l int64, err error) {
-	err = p.tpl.Execute(w, p.s)
+	naming := p.envNaming()
+	secrets := map[string]bool{}
+	for _, s := range p.s {
+		secrets[s.Path] = IsSecret(s)
+	}
+	err = p.tpl.Execute(w, struct {
+		Schema   models.SchemaMap
+		Naming   EnvNaming
+		Secrets  map[string]bool
+		Redacted string
+	}{p.s, naming, secrets, RedactedValue})
 	return
 }
+
+// envNaming returns the naming of the env vars, the one of update_from_env if not configured
+func (p *writeToEnvPlugin) envNaming() EnvNaming {
+	if p.naming != nil {
+		return *p.naming
+	}
+	return updateFromEnv.naming
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/imdario/mergo"
 
 	"github.com/thlcodes/genfig/models"
+	"github.com/thlcodes/genfig/plugins"
 
 	"github.com/thlcodes/genfig/util"
 	u "github.com/thlcodes/genfig/util"
@@ -22,7 +23,14 @@ func init() {
 }
 
 //WriteConfig writes
-func WriteConfig(w io.Writer, s models.SchemaMap, config map[string]interface{}, def map[string]interface{}, env string) (err error) {
+func WriteConfig(w io.Writer, s models.SchemaMap, config map[string]interface{}, def map[string]interface{}, env string) error {
+	_, err := WriteConfigAndReturnReferences(w, s, config, def, env)
+	return err
+}
+
+//WriteConfigAndReturnReferences writes the config like WriteConfig and returns the references of
+//its non-string fields by their dotted paths, which are written as zero values, see plugins.ReferencesPlugin
+func WriteConfigAndReturnReferences(w io.Writer, s models.SchemaMap, config map[string]interface{}, def map[string]interface{}, env string) (refs map[string]string, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = u.RecoverError(r)
@@ -38,14 +46,14 @@ func WriteConfig(w io.Writer, s models.SchemaMap, config map[string]interface{},
 	// via an init function
 	buf.Write(u.B("func init() {" + nl + indent + "Envs." + strings.Title(env) + " = "))
 
-	merged := make(map[string]interface{})
-	copyMap(def, &merged)
-	if err := mergo.Merge(&merged, config, mergo.WithOverride); err != nil {
+	merged, err := MergeConfig(def, config)
+	if err != nil {
 		panic(err)
 	}
 
 	// write actual config
-	WriteConfigValue(buf, defaultSchemaRootName, merged, s, 1)
+	refs = map[string]string{}
+	writeConfigValue(buf, defaultSchemaRootName, merged, s, 1, nil, refs)
 
 	// closing bracket of init func
 	buf.Write(u.B(nl + "}" + nl))
@@ -56,30 +64,52 @@ func WriteConfig(w io.Writer, s models.SchemaMap, config map[string]interface{},
 	return
 }
 
+// MergeConfig returns a copy of the default config def, which is overridden by config
+func MergeConfig(def map[string]interface{}, config map[string]interface{}) (map[string]interface{}, error) {
+	merged := make(map[string]interface{})
+	copyMap(def, &merged)
+	if err := mergo.Merge(&merged, config, mergo.WithOverride); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
 //WriteConfigLine writes
 func WriteConfigLine(w io.Writer, p string, k string, v interface{}, s models.SchemaMap, l int) {
-	if l > maxLevel {
-		panic(fmt.Errorf("Maximum of %d levels exceeded", maxLevel))
-	}
+	writeConfigLine(w, p, k, v, s, l, nil, nil)
+}
+
+func writeConfigLine(w io.Writer, p string, k string, v interface{}, s models.SchemaMap, l int, a ancestors, refs map[string]string) {
+	checkLevel(l)
 
 	n := strings.Title(k)
+	// underscores within keys are not part of the names of the schema
+	sn := p + strings.Replace(n, "_", "", -1)
 
-	if _, ex := s[p+n]; !ex {
-		panic(fmt.Errorf("Config property '%s' is not defined in the default config", p+n))
+	if _, ex := s[sn]; !ex {
+		panic(fmt.Errorf("Config property '%s' is not defined in the default config", sn))
 	}
 
-	w.Write(u.B(indents[:l*len(indent)]))
+	w.Write(u.B(indents(l)))
 	w.Write(u.B(n + ": "))
 
-	WriteConfigValue(w, p+n, v, s, l)
+	writeConfigValue(w, sn, v, s, l, a, refs)
 
 	w.Write(u.B("," + nl))
 }
 
 //WriteConfigValue writes
 func WriteConfigValue(w io.Writer, p string, v interface{}, s models.SchemaMap, l int) {
+	writeConfigValue(w, p, v, s, l, nil, nil)
+}
+
+// writeConfigValue writes the value v of the config property p. Values of non-string
+// properties, which are references to be substituted, are written as zero values
+// and added to refs by their dotted paths
+func writeConfigValue(w io.Writer, p string, v interface{}, s models.SchemaMap, l int, a ancestors, refs map[string]string) {
 	switch v.(type) {
 	case map[string]interface{}:
+		a = a.enter(p, v.(map[string]interface{}))
 		w.Write(u.B(p + "{" + nl))
 		keys := []string{}
 		for _k := range v.(map[string]interface{}) {
@@ -89,15 +119,26 @@ func WriteConfigValue(w io.Writer, p string, v interface{}, s models.SchemaMap,
 		for _, _k := range keys {
 			_v := v.(map[string]interface{})[_k]
 			//_o := getOverwriteEntry(o, _k)
-			WriteConfigLine(w, p, _k, _v /*, _o*/, s, l+1)
+			writeConfigLine(w, p, _k, _v /*, _o*/, s, l+1, a, refs)
 		}
-		w.Write(u.B(indents[:l*len(indent)]))
+		w.Write(u.B(indents(l)))
 		w.Write(u.B("}"))
 	case []interface{}:
 		t := &v
 		typ := util.DetectSliceTypeString((*t).([]interface{}))
 		w.Write(u.B(strings.Replace(fmt.Sprintf("%#v", *t), "[]interface {}", typ, 1)))
 	default:
+		if typ := s[p].Content; typ != "string" && plugins.IsReference(v) {
+			if refs != nil {
+				refs[s[p].DotPath()] = v.(string)
+			}
+			if typ == "bool" {
+				w.Write(u.B("false"))
+			} else {
+				w.Write(u.B("0"))
+			}
+			return
+		}
 		t := &v
 		fmt.Fprintf(w, `%#v`, *t)
 	}
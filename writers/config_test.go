@@ -32,6 +32,7 @@ func Test_WriteConfig(t *testing.T) {
 		{"map", map[string]interface{}{"a": map[string]interface{}{"b": 1}}, nil, []string{"A: ConfigA{", "B: 1"}, false},
 		{"map with interface key", map[string]interface{}{"a": map[string]interface{}{"b": 1}}, nil, []string{"A: ConfigA{", "B: 1"}, false},
 		{"map of map", map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1}}}, nil, []string{"A: ConfigA{", "B: ConfigAB{", "C: 1"}, false},
+		{"reference", map[string]interface{}{"s": "${A}", "i": "${B:-1}", "b": "${C}"}, nil, []string{"S: \"${A}\"", "I: 0", "B: false"}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -44,6 +45,9 @@ func Test_WriteConfig(t *testing.T) {
 				"ConfigA":   models.Schema{},
 				"ConfigAB":  models.Schema{},
 				"ConfigABC": models.Schema{},
+				"ConfigS":   models.Schema{Content: "string", Path: "Config_S"},
+				"ConfigI":   models.Schema{Content: "int64", Path: "Config_I"},
+				"ConfigB":   models.Schema{Content: "bool", Path: "Config_B"},
 			}, tt.config, def, "test")
 			if tt.wantErr {
 				require.Error(t, err)
@@ -57,3 +61,15 @@ func Test_WriteConfig(t *testing.T) {
 		})
 	}
 }
+
+func Test_WriteConfigAndReturnReferences(t *testing.T) {
+	s := &strings.Builder{}
+	refs, err := writers.WriteConfigAndReturnReferences(s, models.SchemaMap{
+		"ConfigS": models.Schema{Content: "string", Path: "Config_S"},
+		"ConfigI": models.Schema{Content: "int64", Path: "Config_I"},
+		"ConfigB": models.Schema{Content: "bool", Path: "Config_B"},
+	}, map[string]interface{}{"s": "${A}", "i": "${B:-1}", "b": "${C}"}, map[string]interface{}{"s": "", "i": 1, "b": true}, "Test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"b": "${C}", "i": "${B:-1}"}, refs)
+	assert.NotContains(t, s.String(), "substitutionReferences")
+}
@@ -8,7 +8,10 @@ import (
 
 var (
 	headerTpl = template.Must(template.New("header").Parse(`// Code generated by {{.Cmd}}; DO NOT EDIT.
-
+{{if .Constraint}}
+//go:build {{.Constraint}}
+// +build {{.Constraint}}
+{{end}}
 package {{.Pkg}}
 
 `))
@@ -16,9 +19,16 @@ package {{.Pkg}}
 
 //WriteHeader writes
 func WriteHeader(w io.Writer, pkg string, cmd string) error {
+	return WriteConstrainedHeader(w, pkg, cmd, "")
+}
+
+//WriteConstrainedHeader writes the header of a file, which is only built
+//if the build constraint is satisfied, e.g. 'go1.21'
+func WriteConstrainedHeader(w io.Writer, pkg string, cmd string, constraint string) error {
 	return headerTpl.Execute(w, struct {
-		Pkg     string
-		Cmd     string
-		Created string
-	}{Pkg: pkg, Cmd: cmd, Created: time.Now().Format(time.RFC3339)})
+		Pkg        string
+		Cmd        string
+		Created    string
+		Constraint string
+	}{Pkg: pkg, Cmd: cmd, Created: time.Now().Format(time.RFC3339), Constraint: constraint})
 }
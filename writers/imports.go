@@ -0,0 +1,97 @@
+package writers
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// importSpec is a single import, the name is the one used in code
+type importSpec struct {
+	name  string
+	path  string
+	alias bool
+}
+
+// parseImport parses an import given as 'path' or as 'name path'
+func parseImport(imp string) (importSpec, error) {
+	fields := strings.Fields(imp)
+	switch len(fields) {
+	case 1:
+		return importSpec{name: path.Base(fields[0]), path: fields[0]}, nil
+	case 2:
+		return importSpec{name: fields[0], path: fields[1], alias: true}, nil
+	default:
+		return importSpec{}, fmt.Errorf("Invalid import '%s', expected 'path' or 'name path'", imp)
+	}
+}
+
+// WriteImports writes one import block with all (unique) imports used by body.
+// If body cannot be parsed, all imports are written
+func WriteImports(w io.Writer, body []byte, imports ...[]string) error {
+	used, parsed := usedPackages(body)
+	specs := map[string]importSpec{}
+	names := map[string]string{}
+	for _, list := range imports {
+		for _, imp := range list {
+			spec, err := parseImport(imp)
+			if err != nil {
+				return err
+			}
+			if name, exists := names[spec.path]; exists && name != spec.name {
+				return fmt.Errorf("Import '%s' is used with different names '%s' and '%s'", spec.path, name, spec.name)
+			}
+			names[spec.path] = spec.name
+			if !parsed || used[spec.name] {
+				specs[spec.path] = spec
+			}
+		}
+	}
+	if len(specs) == 0 {
+		return nil
+	}
+	paths := []string{}
+	for p := range specs {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	if _, err := fmt.Fprintln(w, "import ("); err != nil {
+		return err
+	}
+	for _, p := range paths {
+		spec := specs[p]
+		line := fmt.Sprintf("\t%q", spec.path)
+		if spec.alias {
+			line = fmt.Sprintf("\t%s %q", spec.name, spec.path)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprint(w, ")\n\n")
+	return err
+}
+
+// usedPackages returns the names of all identifiers used as selector
+// in body, e.g. 'fmt' for 'fmt.Println'
+func usedPackages(body []byte) (map[string]bool, bool) {
+	used := map[string]bool{}
+	f, err := parser.ParseFile(token.NewFileSet(), "", append([]byte("package p\n"), body...), 0)
+	if err != nil {
+		return used, false
+	}
+	ast.Inspect(f, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := sel.X.(*ast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+	return used, true
+}
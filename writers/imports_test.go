@@ -0,0 +1,41 @@
+package writers_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/thlcodes/genfig/writers"
+)
+
+func Test_WriteImports(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		imports [][]string
+		want    string
+		wantErr bool
+	}{
+		{"none", "func A() {}", nil, "", false},
+		{"unused", "func A() {}", [][]string{{"fmt"}}, "", false},
+		{"used", "func A() { fmt.Println(os.Args) }", [][]string{{"os", "fmt"}}, "import (\n\t\"fmt\"\n\t\"os\"\n)\n\n", false},
+		{"merged and deduplicated", "func A() { fmt.Println(json.Marshal) }", [][]string{{"fmt"}, {"encoding/json", "fmt"}}, "import (\n\t\"encoding/json\"\n\t\"fmt\"\n)\n\n", false},
+		{"named", "var _ = yaml.Marshal", [][]string{{"yaml gopkg.in/yaml.v3"}}, "import (\n\tyaml \"gopkg.in/yaml.v3\"\n)\n\n", false},
+		{"unparsable body", "func {", [][]string{{"fmt"}}, "import (\n\t\"fmt\"\n)\n\n", false},
+		{"invalid import", "", [][]string{{"a b c"}}, "", true},
+		{"conflicting names", "", [][]string{{"a x/y", "b x/y"}}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := writers.WriteImports(buf, []byte(tt.body), tt.imports...)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
@@ -1,50 +1,136 @@
 package writers
 
 import (
+	"bytes"
+	"fmt"
 	"io"
-	"sort"
 	"text/template"
-)
 
-var (
-	initTpl = template.Must(template.New("init").Parse(`import (
-	"os"
-	"fmt"
+	"github.com/thlcodes/genfig/plugins"
 )
 
-var (
-	_ = os.Getenv
-	_ = fmt.Printf
+// Modes of handling errors reported by the plugins on init
+const (
+	InitErrorsIgnore = "ignore"
+	InitErrorsLog    = "log"
+	InitErrorsPanic  = "panic"
 )
 
-// Current is the current config, selected by the curren env and
+var (
+	initImports = []string{"errors", "fmt", "os", "strings"}
+	initTpl     = template.Must(template.New("init").Parse(`// Current is the current config, selected by the curren env and
 // updated by the availalbe env vars
 var Current *Config
 
-// This init tries to retrieve the current environment via the
-// common env var 'ENV' and applies activated plugins
+// InitError holds the error of the last call of Init, nil if there was none
+var InitError error
+
+// recordSource records the source of a value, which a plugin set on c, e.g. 'env var PORT'.
+// It does nothing, unless replaced by a plugin like 'provenance'
+var recordSource = func(c *Config, path string, source string) {}
+
+// Errors holds multiple errors, e.g. the ones reported by the plugins on Init
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d error(s): %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Init sets Current to a copy of the config of the environment
+// given by the common env var 'ENV' and applies activated plugins.
+// The returned error, also stored in InitError, is of type Errors.
+// Init is called on init, but can be called again, e.g. after env vars changed
+func Init() error {
+	c, _ := Get(os.Getenv("ENV"))
+	copied := *c
+	Current = &copied
+	InitError = nil
+	if errs := applyPlugins(Current); len(errs) > 0 {
+		InitError = Errors(errs)
+	}
+	return InitError
+}
+
+// MustInit calls Init and panics, if it fails
+func MustInit() {
+	if err := Init(); err != nil {
+		panic(err)
+	}
+}
+
+// This init calls Init.
+{{- if eq .Mode "panic"}}
+// It panics, if Init fails
+func init() {
+	MustInit()
+}
+{{- else if eq .Mode "log"}}
+// Errors are printed to stderr, they are also stored in InitError
+func init() {
+	if err := Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "genfig: %v\n", err)
+	}
+}
+{{- else}}
+// Errors are ignored, but stored in InitError
 func init() {
-	Current, _ = Get(os.Getenv("ENV"))
-	{{range $_, $v := .PluginCalls}}
-	{{$v}}
-	{{end}}
+	_ = Init()
+}
+{{- end}}
+
+// applyPlugins calls the init methods of all activated plugins on c
+// and returns all errors they reported
+func applyPlugins(c *Config) []error {
+	errs := []error{}
+{{- range .Calls}}
+{{- if eq .Returns "error"}}
+	if err := c.{{.Method}}(); err != nil {
+		errs = append(errs, err)
+	}
+{{- else if eq .Returns "[]error"}}
+	errs = append(errs, c.{{.Method}}()...)
+{{- else if eq .Returns "bool"}}
+	if !c.{{.Method}}() {
+		errs = append(errs, errors.New("{{.Method}} failed"))
+	}
+{{- else}}
+	c.{{.Method}}()
+{{- end}}
+{{- end}}
+	return errs
 }
-	
 `))
 )
 
-//WriteInit writes
-func WriteInit(w io.Writer, pluginCalls map[string]string) error {
-	keys := []string{}
-	for k := range pluginCalls {
-		keys = append(keys, k)
+//WriteInit writes Init and the init function, which call the given init calls in their order.
+//The mode defines how init handles errors, it defaults to InitErrorsLog
+func WriteInit(w io.Writer, calls []plugins.InitCall, mode string) error {
+	switch mode {
+	case "":
+		mode = InitErrorsLog
+	case InitErrorsIgnore, InitErrorsLog, InitErrorsPanic:
+	default:
+		return fmt.Errorf("Invalid init errors mode '%s', expected one of '%s', '%s' or '%s'", mode, InitErrorsIgnore, InitErrorsLog, InitErrorsPanic)
+	}
+	for _, c := range calls {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	body := &bytes.Buffer{}
+	if err := initTpl.Execute(body, struct {
+		Calls []plugins.InitCall
+		Mode  string
+	}{calls, mode}); err != nil {
+		return err
 	}
-	sort.Strings(keys)
-	calls := []string{}
-	for _, k := range keys {
-		calls = append(calls, pluginCalls[k])
+	if err := WriteImports(w, body.Bytes(), initImports); err != nil {
+		return err
 	}
-	return initTpl.Execute(w, struct {
-		PluginCalls []string
-	}{PluginCalls: calls})
+	_, err := body.WriteTo(w)
+	return err
 }
@@ -5,16 +5,55 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/thlcodes/genfig/plugins"
 	"github.com/thlcodes/genfig/writers"
 )
 
 func Test_WriteInit(t *testing.T) {
 	buf := bytes.NewBuffer(nil)
-	assert.NoError(t, writers.WriteInit(buf, map[string]string{
-		"99_A": "A()",
-		"20_B": "B()",
-		"40_C": "C()",
-		"D":    "D()",
-	}))
-	assert.NotEmpty(t, buf.String())
+	assert.NoError(t, writers.WriteInit(buf, []plugins.InitCall{
+		{Method: "A"},
+		{Method: "B", Returns: plugins.ReturnsError},
+		{Method: "C", Returns: plugins.ReturnsErrors},
+		{Method: "D", Returns: plugins.ReturnsBool},
+	}, ""))
+	got := buf.String()
+	assert.Contains(t, got, "\tc.A()\n")
+	assert.Contains(t, got, "if err := c.B(); err != nil {")
+	assert.Contains(t, got, "errs = append(errs, c.C()...)")
+	assert.Contains(t, got, "if !c.D() {")
+	assert.Contains(t, got, "\t\"errors\"\n")
+
+	buf.Reset()
+	assert.NoError(t, writers.WriteInit(buf, nil, writers.InitErrorsLog))
+	assert.NotContains(t, buf.String(), "\"errors\"", "unused imports are left out")
+
+	assert.Error(t, writers.WriteInit(buf, []plugins.InitCall{{Method: "A", Returns: "int"}}, ""))
+}
+
+func Test_WriteInit_Modes(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{"", "fmt.Fprintf(os.Stderr, \"genfig: %v\\n\", err)", false},
+		{writers.InitErrorsLog, "fmt.Fprintf(os.Stderr, \"genfig: %v\\n\", err)", false},
+		{writers.InitErrorsIgnore, "_ = Init()", false},
+		{writers.InitErrorsPanic, "func init() {\n\tMustInit()\n}", false},
+		{"nope", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			buf := bytes.NewBuffer(nil)
+			err := writers.WriteInit(buf, nil, tt.mode)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Contains(t, buf.String(), tt.want)
+			assert.Contains(t, buf.String(), "func Init() error {")
+		})
+	}
 }
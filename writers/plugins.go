@@ -1,9 +1,11 @@
 package writers
 
 import (
+	"bytes"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/thlcodes/genfig/plugins"
 
@@ -14,29 +16,89 @@ const (
 	pluginPrefix = "plugin_"
 )
 
-//WritePlugins writes a plugin file for each plugin
-func WritePlugins(schema models.SchemaMap, dir string, pkg string, cmd string, calls map[string]string) ([]string, error) {
+//WritePlugins writes a plugin file for each of the given plugins, plus their constrained
+//files. Files of registered plugins, which are not given, are removed from dir, if they were generated
+func WritePlugins(ps []plugins.Plugin, schema models.SchemaMap, dir string, pkg string, cmd string) ([]string, error) {
 	files := []string{}
-	for n, p := range plugins.Plugins {
-		orig := n
-		p.SetSchemaMap(schema)
-		if strings.Contains(n, "_") {
-			n = n[strings.Index(n, "_")+1:]
+	selected := map[string]bool{}
+	for _, p := range ps {
+		selected[p.Name()] = true
+	}
+	for _, n := range plugins.Names() {
+		if selected[n] {
+			continue
+		}
+		paths := []string{filepath.Join(dir, pluginPrefix+n+".go")}
+		if cp, ok := plugins.Plugins[n].(plugins.ConstrainedFilesPlugin); ok {
+			for _, cf := range cp.ConstrainedFiles() {
+				paths = append(paths, filepath.Join(dir, pluginPrefix+n+"_"+cf.Name+".go"))
+			}
 		}
+		for _, path := range paths {
+			if err := removeGenerated(path); err != nil {
+				return files, err
+			}
+		}
+	}
+	for _, p := range ps {
+		p.SetSchemaMap(schema)
+		n := p.Name()
 		path := filepath.Join(dir, pluginPrefix+n+".go")
-		if f, err := os.Create(path); err != nil {
-			return files, err
-		} else if err := WriteHeader(f, pkg, cmd+" plugin '"+n+"'"); err != nil {
-			return files, err
-		} else if _, err := p.WriteTo(f); err != nil {
+		if err := writePluginFile(path, pkg, cmd+" plugin '"+n+"'", "", p.Imports(), func(w io.Writer) error {
+			_, err := p.WriteTo(w)
+			return err
+		}); err != nil {
 			return files, err
-		} else {
-			_ = f.Close()
-			files = append(files, path)
 		}
-		if c, has := p.GetInitCall(); has {
-			calls[orig] = c
+		files = append(files, path)
+		cp, ok := p.(plugins.ConstrainedFilesPlugin)
+		if !ok {
+			continue
+		}
+		for _, cf := range cp.ConstrainedFiles() {
+			name := cf.Name
+			path := filepath.Join(dir, pluginPrefix+n+"_"+name+".go")
+			if err := writePluginFile(path, pkg, cmd+" plugin '"+n+"'", cf.Constraint, cf.Imports, func(w io.Writer) error {
+				return cp.WriteConstrainedTo(w, name)
+			}); err != nil {
+				return files, err
+			}
+			files = append(files, path)
 		}
 	}
 	return files, nil
 }
+
+// writePluginFile writes the file at path with the header, the used imports and the body written by write
+func writePluginFile(path string, pkg string, cmd string, constraint string, imports []string, write func(w io.Writer) error) error {
+	body := &bytes.Buffer{}
+	if err := write(body); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := WriteConstrainedHeader(f, pkg, cmd, constraint); err != nil {
+		return err
+	} else if err := WriteImports(f, body.Bytes(), imports); err != nil {
+		return err
+	}
+	_, err = body.WriteTo(f)
+	return err
+}
+
+// removeGenerated removes the file at path, if it exists and was generated
+func removeGenerated(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(data, []byte("// Code generated by")) {
+		return nil
+	}
+	return os.Remove(path)
+}
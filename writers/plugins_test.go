@@ -3,6 +3,7 @@ package writers_test
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,13 +14,30 @@ import (
 
 func Test_WritePlugins(t *testing.T) {
 	sm := models.SchemaMap{}
-	calls := map[string]string{}
 	dir, _ := ioutil.TempDir("", "genfig")
 	defer os.RemoveAll(dir)
-	files, err := writers.WritePlugins(sm, dir, "test", "genfig test", calls)
+	all, _ := plugins.Plugins.Ordered()
+	files, err := writers.WritePlugins(all, sm, dir, "test", "genfig test")
 	assert.NoError(t, err)
-	assert.Len(t, files, len(plugins.Plugins))
+	// plus the constrained file of 'redact'
+	assert.Len(t, files, len(plugins.Plugins)+1)
 	for _, f := range files {
 		assert.FileExists(t, f)
 	}
+	slog, _ := ioutil.ReadFile(filepath.Join(dir, "plugin_redact_slog.go"))
+	assert.Contains(t, string(slog), "// Code generated by genfig test plugin 'redact'; DO NOT EDIT.\n\n//go:build go1.21\n// +build go1.21\n\npackage test\n")
+
+	// unselected plugins are removed, if generated
+	selected := []plugins.Plugin{plugins.Plugins["map"]}
+	manual := filepath.Join(dir, "plugin_config_test.go")
+	files, err = writers.WritePlugins(selected, sm, dir, "test", "genfig test")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "plugin_map.go")}, files)
+	assert.NoFileExists(t, manual)
+	assert.NoFileExists(t, filepath.Join(dir, "plugin_redact_slog.go"))
+
+	_ = ioutil.WriteFile(manual, []byte("package test"), 0666)
+	_, err = writers.WritePlugins(selected, sm, dir, "test", "genfig test")
+	assert.NoError(t, err)
+	assert.FileExists(t, manual, "not generated")
 }
@@ -15,8 +15,9 @@ const (
 	defaultSchemaRootName = "Config"
 )
 
-//WriteAndReturnSchema writes
-func WriteAndReturnSchema(w io.Writer, c map[string]interface{}) (s models.SchemaMap, err error) {
+//WriteAndReturnSchema writes the schema of the config c and returns it.
+//The additional fields, e.g. 'sources *valueSources', are added to the top level type
+func WriteAndReturnSchema(w io.Writer, c map[string]interface{}, fields ...string) (s models.SchemaMap, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = u.RecoverError(r)
@@ -26,11 +27,16 @@ func WriteAndReturnSchema(w io.Writer, c map[string]interface{}) (s models.Schem
 
 	s = models.SchemaMap{}
 	// using NoopWriter since top level is not needed
-	WriteSchema(u.NoopWriter{}, defaultSchemaRootName, c, s, 0)
+	writeSchema(u.NoopWriter{}, defaultSchemaRootName, nil, c, s, 0, nil)
 
 	buf := bytes.NewBuffer([]byte{})
 	// write top level schema type definition (usually 'Config')
-	buf.Write(u.B("type " + defaultSchemaRootName + " " + s[defaultSchemaRootName].Content + nl))
+	root := s[defaultSchemaRootName].Content
+	if len(fields) > 0 {
+		end := strings.LastIndex(root, "}")
+		root = root[:end] + indent + strings.Join(fields, nl+indent) + nl + root[end:]
+	}
+	buf.Write(u.B("type " + defaultSchemaRootName + " " + root + nl))
 	keys := []string{}
 	for k := range s {
 		keys = append(keys, k)
@@ -53,18 +59,22 @@ func WriteAndReturnSchema(w io.Writer, c map[string]interface{}) (s models.Schem
 
 //WriteSchema writes
 func WriteSchema(w io.Writer, k string, v interface{}, s models.SchemaMap, l int) bool {
-	if l > maxLevel {
-		panic(fmt.Errorf("Maximum of %d levels exceeded", maxLevel))
-	}
+	return writeSchema(w, k, nil, v, s, l, nil)
+}
+
+// writeSchema writes the schema of the value v at the path k, which is reached by the config keys
+func writeSchema(w io.Writer, k string, keys []string, v interface{}, s models.SchemaMap, l int, a ancestors) bool {
+	checkLevel(l)
 	b := bytes.NewBuffer([]byte{})
 	n := strings.Title(k)
-	isStruct := WriteSchemaType(b, n, v, s, l)
+	isStruct := writeSchemaType(b, n, keys, v, s, l, a)
 
 	n = strings.Replace(n, "_", "", -1)
 	s[n] = models.Schema{
 		IsStruct: isStruct,
 		Content:  b.String(),
 		Path:     k,
+		Keys:     keys,
 	}
 	w.Write(b.Bytes())
 
@@ -74,9 +84,14 @@ func WriteSchema(w io.Writer, k string, v interface{}, s models.SchemaMap, l int
 // WriteSchemaType the type text to a writer and returns, if type is a struct or not
 //WriteSchemaType writes
 func WriteSchemaType(w io.Writer, p string, v interface{}, s models.SchemaMap, l int) (isStruct bool) {
+	return writeSchemaType(w, p, nil, v, s, l, nil)
+}
+
+func writeSchemaType(w io.Writer, p string, parents []string, v interface{}, s models.SchemaMap, l int, a ancestors) (isStruct bool) {
 	switch v.(type) {
 	case map[string]interface{}:
 		isStruct = true
+		a = a.enter(p, v.(map[string]interface{}))
 		buf := bytes.NewBuffer([]byte{})
 		w.Write(u.B("struct {" + nl))
 		keys := []string{}
@@ -86,10 +101,11 @@ func WriteSchemaType(w io.Writer, p string, v interface{}, s models.SchemaMap, l
 		sort.Strings(keys)
 		for _, _k := range keys {
 			_v := v.(map[string]interface{})[_k]
+			_keys := append(append([]string{}, parents...), _k)
 			_k = strings.Title(_k)
-			_isStruct := WriteSchema(buf, p+"_"+_k, _v, s, l+1)
+			_isStruct := writeSchema(buf, p+"_"+_k, _keys, _v, s, l+1, a)
 			if _isStruct {
-				w.Write(u.B(indent + _k + " " + strings.Replace(p, "_", "", -1) + _k + nl))
+				w.Write(u.B(indent + _k + " " + strings.Replace(p+_k, "_", "", -1) + nl))
 			} else {
 				w.Write(u.B(indent + _k + " " + buf.String() + nl))
 			}
@@ -50,6 +50,13 @@ func Test_WriteSchema(t *testing.T) {
 	}
 }
 
+func Test_WriteAndReturnSchema_Fields(t *testing.T) {
+	s := &strings.Builder{}
+	_, err := writers.WriteAndReturnSchema(s, map[string]interface{}{"a": "b"}, "sources *valueSources", "x int")
+	require.NoError(t, err)
+	assert.Contains(t, s.String(), "type Config struct {n  A stringn  sources *valueSourcesn  x intn}")
+}
+
 func Benchmark_WriteSchemaType(b *testing.B) {
 	w := util.NoopWriter{}
 	s := models.SchemaMap{}
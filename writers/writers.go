@@ -1,31 +1,59 @@
 package writers
 
-import "strings"
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
 
 var (
 	indent   = "\t" // default is two spaces
-	maxLevel = 5    // default is 5 maximum levels of recursion
+	maxLevel = 0    // default is 0, meaning unlimited levels of recursion
 	nl       = "\n" // default is *nix new line
-
-	indents = strings.Repeat(indent, maxLevel+1)
 )
 
 // SetIndent sets the indent to be used by the writers
 // to indent recursive data
 func SetIndent(s string) {
 	indent = s
-	indents = strings.Repeat(indent, maxLevel+1)
 }
 
 // SetMaxLevel sets the maximum level of recursion;
 // If one configuration exceeds this maximum level,
-// the generation fails
+// the generation fails. 0 (default) disables the limit.
 func SetMaxLevel(l int) {
 	maxLevel = l
-	indents = strings.Repeat(indent, maxLevel+1)
 }
 
 // SetNewline sets the new line to be used by the writers
 func SetNewline(s string) {
 	nl = s
 }
+
+// indents returns the indentation for level l
+func indents(l int) string {
+	return strings.Repeat(indent, l)
+}
+
+// checkLevel panics, if l exceeds the maximum level (if set)
+func checkLevel(l int) {
+	if maxLevel > 0 && l > maxLevel {
+		panic(fmt.Errorf("Maximum of %d levels exceeded", maxLevel))
+	}
+}
+
+// ancestors holds the maps of the current recursion path,
+// so that cyclic configs are detected instead of recursing endlessly
+type ancestors []uintptr
+
+// enter returns the ancestors including m and panics,
+// if m is already one of the ancestors
+func (a ancestors) enter(p string, m map[string]interface{}) ancestors {
+	ptr := reflect.ValueOf(m).Pointer()
+	for _, anc := range a {
+		if anc == ptr {
+			panic(fmt.Errorf("Cyclic config detected at '%s'", p))
+		}
+	}
+	return append(a[:len(a):len(a)], ptr)
+}
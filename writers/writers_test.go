@@ -1,6 +1,12 @@
 package writers_test
 
-import "github.com/thlcodes/genfig/writers"
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/thlcodes/genfig/util"
+	"github.com/thlcodes/genfig/writers"
+)
 
 const (
 	maxLevel = 10
@@ -13,3 +19,40 @@ func init() {
 	writers.SetMaxLevel(maxLevel)
 	writers.SetNewline(newLine)
 }
+
+func deepMap(levels int) map[string]interface{} {
+	m := map[string]interface{}{"v": 1}
+	for i := 0; i < levels; i++ {
+		m = map[string]interface{}{"a": m}
+	}
+	return m
+}
+
+func Test_Levels(t *testing.T) {
+	cyclic := map[string]interface{}{"a": 1}
+	cyclic["b"] = map[string]interface{}{"c": cyclic}
+
+	tests := []struct {
+		name     string
+		maxLevel int
+		config   map[string]interface{}
+		wantErr  string
+	}{
+		{"unlimited", 0, deepMap(20), ""},
+		{"within limit", 5, deepMap(4), ""},
+		{"limit exceeded", 5, deepMap(5), "Maximum of 5 levels exceeded"},
+		{"cycle", 0, cyclic, "Cyclic config detected at 'Config_B_C'"},
+	}
+	defer writers.SetMaxLevel(maxLevel)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writers.SetMaxLevel(tt.maxLevel)
+			_, err := writers.WriteAndReturnSchema(util.NoopWriter{}, tt.config)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}